@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic is a problem of a spec, which prevents code generation, at its
+// position in the .ksy file.
+type Diagnostic struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Msg)
+}
+
+// Diagnostics are all problems found in a spec.
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	msgs := make([]string, len(d))
+	for i, diagnostic := range d {
+		msgs[i] = diagnostic.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// checker collects the diagnostics of a spec. Expressions are checked in the
+// scope of the type, _ is of type current.
+type checker struct {
+	file    string
+	diags   Diagnostics
+	scope   string
+	current string
+}
+
+// report adds a diagnostic at the value of key in node, or at node itself if
+// key is not set.
+func (c *checker) report(node *yaml.Node, key string, format string, args ...interface{}) {
+	if value := valueNode(node, key); value != nil {
+		node = value
+	}
+	d := Diagnostic{File: c.file, Msg: fmt.Sprintf(format, args...)}
+	if node != nil {
+		d.Line, d.Column = node.Line, node.Column
+	}
+	c.diags = append(c.diags, d)
+}
+
+// expr reports expr, if it is set and invalid or not of the kind want, which
+// is "bool", "int" or empty for any type.
+func (c *checker) expr(node *yaml.Node, key string, expr string, want string) {
+	if expr == "" {
+		return
+	}
+	t, err := checkExpr(expr, c.scope, c.current)
+	switch {
+	case err != nil:
+		c.report(node, key, "%s: %v", key, err)
+	case t == "":
+	case want == "bool" && t != "bool":
+		c.report(node, key, "%s: %q must be a boolean, not %s", key, expr, t)
+	case want == "int" && !isIntType(t):
+		c.report(node, key, "%s: %q must be an integer, not %s", key, expr, t)
+	}
+}
+
+// encoding reports the encoding of node, if it is not supported by the
+// runtime.
+func (c *checker) encoding(node *yaml.Node, encoding string) {
+	if encoding != "" && !runtime.IsKnownEncoding(encoding) {
+		c.report(node, "encoding", "unknown encoding %q", encoding)
+	}
+}
+
+// Check validates k and its subtypes, before code is generated. It returns
+// all problems found or nil.
+func (k *Type) Check(file string) error {
+	c := &checker{file: file}
+	k.check(c)
+	if len(c.diags) == 0 {
+		return nil
+	}
+	return c.diags
+}
+
+func (k *Type) check(c *checker) {
+	c.scope, c.current = k.name, ""
+	meta := valueNode(k.node, "meta")
+	c.expr(valueNode(meta, "endian"), "switch-on", k.Meta.Endian.SwitchOn, "")
+	c.encoding(meta, k.Meta.Encoding)
+	for _, endian := range k.Meta.Endian.Cases {
+		if endian != "le" && endian != "be" {
+			c.report(valueNode(meta, "endian"), "cases", "invalid endian %q, must be le or be", endian)
+		}
+	}
+	for _, attr := range k.Seq {
+		attr.check(c)
+	}
+	for _, name := range k.InstanceNames() {
+		inst := k.Instances[name]
+		inst.check(c)
+	}
+	for _, name := range k.TypeNames() {
+		t := k.Types[name]
+		t.check(c)
+	}
+}
+
+func (k *Attribute) check(c *checker) {
+	switch k.Repeat {
+	case "", "eos":
+	case "expr":
+		if k.RepeatExpr == "" {
+			c.report(k.node, "repeat", "repeat: expr needs repeat-expr")
+		}
+	case "until":
+		if k.RepeatUntil == "" {
+			c.report(k.node, "repeat", "repeat: until needs repeat-until")
+		}
+	default:
+		c.report(k.node, "repeat", "invalid repeat %q, must be eos, expr or until", k.Repeat)
+	}
+	if err := k.Contents.Check(); err != nil {
+		c.report(k.node, "contents", "contents: %v", err)
+	}
+	c.encoding(k.node, k.Encoding)
+
+	c.current = ""
+	c.expr(k.node, "size", k.Size, "int")
+	c.expr(k.node, "repeat-expr", k.RepeatExpr, "int")
+	c.expr(k.node, "value", k.Value, "")
+	c.expr(k.node, "pos", k.Pos, "int")
+	c.expr(k.node, "io", k.IO, "")
+	c.expr(k.node, "if", k.If, "bool")
+
+	typeNode := valueNode(k.node, "type")
+	c.expr(typeNode, "switch-on", k.Type.TypeSwitch.SwitchOn, "")
+	for _, arg := range k.Type.Args {
+		c.expr(k.node, "type", arg, "")
+	}
+
+	validNode := valueNode(k.node, "valid")
+	if validNode != nil && validNode.Kind == yaml.ScalarNode {
+		c.expr(k.node, "valid", k.Valid.Eq, "")
+	} else {
+		c.expr(validNode, "eq", k.Valid.Eq, "")
+	}
+	c.expr(validNode, "min", k.Valid.Min, "")
+	c.expr(validNode, "max", k.Valid.Max, "")
+	for _, value := range k.Valid.AnyOf {
+		c.expr(validNode, "any-of", value, "")
+	}
+
+	// _ is the value just read
+	c.current = k.ChildType()
+	c.expr(k.node, "repeat-until", k.RepeatUntil, "bool")
+	c.expr(validNode, "expr", k.Valid.Expr, "bool")
+}
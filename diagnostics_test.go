@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const brokenSpec = `
+meta:
+  id: broken
+seq:
+  - id: count
+    type: u1
+  - id: items
+    type: u1
+    repeat: expr
+  - id: flags
+    type: u1
+    valid: 0b102
+instances:
+  magic:
+    pos: 0
+    contents: [0x50, 300]
+types:
+  body:
+    seq:
+      - id: data
+        size: (count +
+`
+
+func TestCheck(t *testing.T) {
+	var kaitai Type
+	if err := yaml.Unmarshal([]byte(brokenSpec), &kaitai); err != nil {
+		t.Fatal(err)
+	}
+	err := kaitai.Check("broken.ksy")
+	diags, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("expected diagnostics, got %v", err)
+	}
+	assert.Equal(t, Diagnostics{
+		{File: "broken.ksy", Line: 9, Column: 13, Msg: "repeat: expr needs repeat-expr"},
+		{File: "broken.ksy", Line: 12, Column: 12, Msg: `valid: invalid expression "0b102": column 1: invalid binary literal 0b102`},
+		{File: "broken.ksy", Line: 16, Column: 15, Msg: "contents: contents value 300 is not a byte"},
+		{File: "broken.ksy", Line: 21, Column: 15, Msg: `size: invalid expression "(count +": column 9: expected operand, found end of expression`},
+	}, diags)
+	assert.Equal(t, "broken.ksy:9:13: repeat: expr needs repeat-expr", diags[0].Error())
+}
+
+func TestCheckValid(t *testing.T) {
+	var kaitai Type
+	if err := yaml.Unmarshal([]byte(renderSpec), &kaitai); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, kaitai.Check("render.ksy"))
+}
+
+func TestCheckMissingID(t *testing.T) {
+	var kaitai Type
+	spec := "meta:\n  id: anonymous\nseq:\n  - id: len\n    type: u1\n  - size: len\n"
+	if err := yaml.Unmarshal([]byte(spec), &kaitai); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, kaitai.Check("anonymous.ksy"))
+	assert.Equal(t, "_unnamed1", kaitai.Seq[1].ID)
+	assert.Equal(t, "unnamed1", kaitai.Seq[1].Name())
+}
+
+func TestCheckEncoding(t *testing.T) {
+	var kaitai Type
+	spec := "meta:\n  id: encodings\n  encoding: EBCDIC\nseq:\n  - id: name\n    type: strz\n    encoding: SJIS\n  - id: title\n    size: 4\n    encoding: UTF-7\n"
+	if err := yaml.Unmarshal([]byte(spec), &kaitai); err != nil {
+		t.Fatal(err)
+	}
+	err := kaitai.Check("encodings.ksy")
+	assert.Equal(t, Diagnostics{
+		{File: "encodings.ksy", Line: 3, Column: 13, Msg: `unknown encoding "EBCDIC"`},
+		{File: "encodings.ksy", Line: 10, Column: 15, Msg: `unknown encoding "UTF-7"`},
+	}, err)
+}
@@ -1,16 +1,66 @@
 package main
 
+import (
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// enumTypes maps the path of an enum, e.g. "container1::animal", to its Go
+// type name.
 var enumTypes map[string]string
 
-func addEnumType(enumName, enumType string) {
-	if _, ok := enumTypes[enumName]; !ok {
-		enumTypes[enumName] = enumType
+func addEnumType(enumPath, enumType string) {
+	if _, ok := enumTypes[enumPath]; !ok {
+		enumTypes[enumPath] = enumType
 	}
 }
 
+// getEnumType resolves an enum reference to its Go type name. References are
+// either the full path of the enum or a suffix of it, the shortest matching
+// path wins.
 func getEnumType(enumName string) string {
 	if enumType, ok := enumTypes[enumName]; ok {
 		return enumType
 	}
-	return "int64"
+	var paths []string
+	for path := range enumTypes {
+		if strings.HasSuffix(path, "::"+enumName) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return strcase.ToCamel(enumName[strings.LastIndex(enumName, ":")+1:])
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) < len(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+	return enumTypes[paths[0]]
+}
+
+func isEnumType(dataType string) bool {
+	for _, enumType := range enumTypes {
+		if enumType == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+// setupEnums registers the enums of k and its subtypes. Enums of the root type
+// are named like the enum, nested enums are prefixed with their type path.
+func setupEnums(k *Type) {
+	for _, name := range k.EnumNames() {
+		enumPath := k.SubPath(name)
+		addEnumType(enumPath, strcase.ToCamel(strings.ReplaceAll(enumPath, "::", "_")))
+	}
+	for _, name := range k.TypeNames() {
+		t := k.Types[name]
+		t.path = k.SubPath(name)
+		setupEnums(&t)
+	}
 }
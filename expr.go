@@ -0,0 +1,536 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exprNode is a node of a parsed Kaitai expression.
+type exprNode interface{}
+
+type (
+	// intLit is an integer literal, Text is a valid Go literal.
+	intLit struct{ Text string }
+	// floatLit is a floating point literal, Text is a valid Go literal.
+	floatLit struct{ Text string }
+	// strLit is a string literal with its escapes resolved.
+	strLit  struct{ Value string }
+	boolLit struct{ Value bool }
+	// arrayLit is a byte array literal, e.g. [0x50, 0x4b].
+	arrayLit struct{ Elems []exprNode }
+	// enumLit is an enum value, e.g. animal::dog or container::animal::dog.
+	enumLit struct{ Path []string }
+	// ident is the name of an attribute or a special name like _io.
+	ident struct{ Name string }
+	// member is a field or method of X. Calls have parentheses, even without
+	// arguments.
+	member struct {
+		X    exprNode
+		Name string
+		Call bool
+		Args []exprNode
+	}
+	indexExpr struct{ X, Index exprNode }
+	// castExpr is X.as<Type>.
+	castExpr struct {
+		X    exprNode
+		Type string
+	}
+	unaryExpr struct {
+		Op string
+		X  exprNode
+	}
+	binaryExpr struct {
+		Op   string
+		X, Y exprNode
+	}
+	ternaryExpr struct{ Cond, Then, Else exprNode }
+	parenExpr   struct{ X exprNode }
+)
+
+// exprError is a syntax error at the byte offset Pos of an expression.
+type exprError struct {
+	Pos int
+	Msg string
+}
+
+func (e *exprError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokInt
+	tokFloat
+	tokString
+	tokIdent
+	tokOp
+)
+
+type exprToken struct {
+	Kind tokenKind
+	Text string // Go literal of numbers, value of strings
+	Pos  int
+}
+
+func (t exprToken) String() string {
+	switch t.Kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.Text)
+	}
+	return "'" + t.Text + "'"
+}
+
+// exprOps are the operators, longer ones first.
+var exprOps = []string{
+	"::", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+", "-", "*", "/", "%", "&", "|", "^", "~", "<", ">", "!",
+	"(", ")", "[", "]", ",", ".", "?", ":",
+}
+
+func isIdentRune(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// lexExpr splits an expression into tokens.
+func lexExpr(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9':
+			tok, n, err := lexNumber(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i += n
+		case isIdentRune(c):
+			start := i
+			for i < len(expr) && isIdentRune(expr[i]) {
+				i++
+			}
+			tokens = append(tokens, exprToken{Kind: tokIdent, Text: expr[start:i], Pos: start})
+		case c == '\'' || c == '"':
+			value, n, err := lexString(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, exprToken{Kind: tokString, Text: value, Pos: i})
+			i += n
+		default:
+			op := ""
+			for _, candidate := range exprOps {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &exprError{i, fmt.Sprintf("unexpected character %q", expr[i:i+1])}
+			}
+			tokens = append(tokens, exprToken{Kind: tokOp, Text: op, Pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, exprToken{Kind: tokEOF, Pos: len(expr)}), nil
+}
+
+// lexNumber reads the number at pos and returns it with the length of its
+// text. Binary literals become decimal.
+func lexNumber(expr string, pos int) (exprToken, int, error) {
+	end := pos
+	for end < len(expr) && isIdentRune(expr[end]) {
+		end++
+	}
+	text := expr[pos:end]
+	digits := strings.Replace(text, "_", "", -1)
+	if len(digits) > 1 && digits[0] == '0' {
+		base := map[byte]int{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}[digits[1]]
+		if base != 0 {
+			num, err := strconv.ParseUint(digits[2:], base, 64)
+			if err != nil {
+				if base == 2 {
+					return exprToken{}, 0, &exprError{pos, "invalid binary literal " + text}
+				}
+				return exprToken{}, 0, &exprError{pos, "invalid integer literal " + text}
+			}
+			if base == 2 {
+				digits = strconv.FormatUint(num, 10)
+			}
+			return exprToken{Kind: tokInt, Text: digits, Pos: pos}, end - pos, nil
+		}
+	}
+
+	// floats have a fraction or an exponent
+	float := false
+	if end+1 < len(expr) && expr[end] == '.' && expr[end+1] >= '0' && expr[end+1] <= '9' {
+		float = true
+		for end++; end < len(expr) && isIdentRune(expr[end]); end++ {
+		}
+	}
+	if e := strings.LastIndexAny(expr[pos:end], "eE"); e != -1 && e == end-pos-1 && end < len(expr) && (expr[end] == '+' || expr[end] == '-') {
+		// signed exponent
+		for end++; end < len(expr) && expr[end] >= '0' && expr[end] <= '9'; end++ {
+		}
+	}
+	text = expr[pos:end]
+	digits = strings.Replace(text, "_", "", -1)
+	if float || strings.ContainsAny(digits, "eE") {
+		if _, err := strconv.ParseFloat(digits, 64); err != nil {
+			return exprToken{}, 0, &exprError{pos, "invalid float literal " + text}
+		}
+		return exprToken{Kind: tokFloat, Text: digits, Pos: pos}, end - pos, nil
+	}
+	if _, err := strconv.ParseUint(digits, 10, 64); err != nil {
+		return exprToken{}, 0, &exprError{pos, "invalid integer literal " + text}
+	}
+	return exprToken{Kind: tokInt, Text: digits, Pos: pos}, end - pos, nil
+}
+
+// lexString reads the string literal at pos and returns its value and the
+// length of its text. Single quoted strings have no escapes.
+func lexString(expr string, pos int) (string, int, error) {
+	quote := expr[pos]
+	var value strings.Builder
+	for i := pos + 1; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == quote:
+			return value.String(), i + 1 - pos, nil
+		case c == '\\' && quote == '"':
+			i++
+			if i == len(expr) {
+				break
+			}
+			n, err := unescape(&value, expr[i:])
+			if err != nil {
+				return "", 0, &exprError{i - 1, err.Error()}
+			}
+			i += n - 1
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", 0, &exprError{pos, "string literal not terminated"}
+}
+
+var escapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 't': '\t', 'n': '\n', 'v': '\v', 'f': '\f', 'r': '\r',
+	'e': 0x1b, '"': '"', '\'': '\'', '\\': '\\',
+}
+
+// unescape writes the character of the escape sequence at the start of s,
+// without the backslash, and returns the length of the sequence.
+func unescape(value *strings.Builder, s string) (int, error) {
+	if c, ok := escapes[s[0]]; ok {
+		value.WriteByte(c)
+		return 1, nil
+	}
+	switch {
+	case s[0] >= '0' && s[0] <= '7':
+		// octal, e.g. \0 or \177
+		n := 1
+		for n < 3 && n < len(s) && s[n] >= '0' && s[n] <= '7' {
+			n++
+		}
+		c, err := strconv.ParseUint(s[:n], 8, 8)
+		if err != nil {
+			return 0, fmt.Errorf("invalid escape \\%s", s[:n])
+		}
+		value.WriteByte(byte(c))
+		return n, nil
+	case s[0] == 'u' && len(s) >= 5:
+		r, err := strconv.ParseUint(s[1:5], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid escape \\%s", s[:5])
+		}
+		value.WriteRune(rune(r))
+		return 5, nil
+	}
+	return 0, fmt.Errorf("invalid escape \\%c", s[0])
+}
+
+// exprParser builds the syntax tree of an expression by recursive descent,
+// one function per precedence level.
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// parseExpr parses a Kaitai expression.
+func parseExpr(expr string) (exprNode, error) {
+	tokens, err := lexExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.Kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the operators or keywords.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.Kind != tokOp && tok.Kind != tokIdent {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.Text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		tok := p.peek()
+		return p.errorf(tok, "expected '%s', found %s", op, tok)
+	}
+	return nil
+}
+
+func (p *exprParser) errorf(tok exprToken, format string, args ...interface{}) error {
+	return &exprError{tok.Pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) ternary() (exprNode, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return cond, nil
+	}
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryExpr{Cond: cond, Then: then, Else: els}, nil
+}
+
+// binaryLevels are the binary operators from the lowest to the highest
+// precedence. The unary not binds weaker than comparisons.
+var binaryLevels = [][]string{
+	{"or", "||"},
+	{"and", "&&"},
+	nil, // not
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) binary(level int) (exprNode, error) {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+	if binaryLevels[level] == nil {
+		if _, ok := p.accept("not", "!"); ok {
+			x, err := p.binary(level)
+			if err != nil {
+				return nil, err
+			}
+			return &unaryExpr{Op: "not", X: x}, nil
+		}
+		return p.binary(level + 1)
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(binaryLevels[level]...)
+		if !ok {
+			return x, nil
+		}
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "||":
+			op = "or"
+		case "&&":
+			op = "and"
+		}
+		x = &binaryExpr{Op: op, X: x, Y: y}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if op, ok := p.accept("-", "+", "~"); ok {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			return x, nil
+		}
+		return &unaryExpr{Op: op, X: x}, nil
+	}
+	return p.postfix()
+}
+
+func (p *exprParser) postfix() (exprNode, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peek().Text == "." && p.peek().Kind == tokOp:
+			p.next()
+			tok := p.next()
+			if tok.Kind != tokIdent {
+				return nil, p.errorf(tok, "expected name, found %s", tok)
+			}
+			if tok.Text == "as" && p.peek().Text == "<" {
+				p.next()
+				typeName, err := p.typeName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(">"); err != nil {
+					return nil, err
+				}
+				x = &castExpr{X: x, Type: typeName}
+				continue
+			}
+			m := &member{X: x, Name: tok.Text}
+			if _, ok := p.accept("("); ok {
+				m.Call = true
+				if m.Args, err = p.list(")"); err != nil {
+					return nil, err
+				}
+			}
+			x = m
+		case p.peek().Text == "[" && p.peek().Kind == tokOp:
+			p.next()
+			index, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &indexExpr{X: x, Index: index}
+		default:
+			return x, nil
+		}
+	}
+}
+
+// typeName parses the type of a cast, which may be a path like a::b.
+func (p *exprParser) typeName() (string, error) {
+	parts := []string{}
+	for {
+		tok := p.next()
+		if tok.Kind != tokIdent {
+			return "", p.errorf(tok, "expected type name, found %s", tok)
+		}
+		parts = append(parts, tok.Text)
+		if _, ok := p.accept("::"); !ok {
+			return strings.Join(parts, "::"), nil
+		}
+	}
+}
+
+// list parses comma separated expressions up to the closing token.
+func (p *exprParser) list(closing string) ([]exprNode, error) {
+	var elems []exprNode
+	if _, ok := p.accept(closing); ok {
+		return elems, nil
+	}
+	for {
+		elem, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+		if _, ok := p.accept(","); !ok {
+			return elems, p.expect(closing)
+		}
+	}
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	tok := p.next()
+	switch tok.Kind {
+	case tokInt:
+		return &intLit{Text: tok.Text}, nil
+	case tokFloat:
+		return &floatLit{Text: tok.Text}, nil
+	case tokString:
+		return &strLit{Value: tok.Text}, nil
+	case tokIdent:
+		switch tok.Text {
+		case "true", "false":
+			return &boolLit{Value: tok.Text == "true"}, nil
+		}
+		if p.peek().Text != "::" {
+			return &ident{Name: tok.Text}, nil
+		}
+		path := []string{tok.Text}
+		for {
+			if _, ok := p.accept("::"); !ok {
+				return &enumLit{Path: path}, nil
+			}
+			name := p.next()
+			if name.Kind != tokIdent {
+				return nil, p.errorf(name, "expected enum name, found %s", name)
+			}
+			path = append(path, name.Text)
+		}
+	case tokOp:
+		switch tok.Text {
+		case "(":
+			x, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			return &parenExpr{X: x}, p.expect(")")
+		case "[":
+			elems, err := p.list("]")
+			return &arrayLit{Elems: elems}, err
+		}
+	}
+	return nil, p.errorf(tok, "expected operand, found %s", tok)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseExpr(t *testing.T) {
+	typeScopes = map[string]*Type{}
+	enumTypes = map[string]string{}
+
+	tests := []Result{
+		// Kaitai binds shifts weaker than +, Go the other way round
+		{Input: "a + b << 2", GoCode: "(k.A() + k.B()) << 2", Type: "int64"},
+		{Input: "a & b == 0", GoCode: "k.A()&k.B() == 0", Type: "bool"},
+		{Input: "a | b ^ c & d", GoCode: "k.A() | (k.B() ^ k.C()&k.D())", Type: "int64"},
+		{Input: "a - (b - c)", GoCode: "k.A() - (k.B() - k.C())", Type: "int64"},
+		{Input: "a - b - c", GoCode: "k.A() - k.B() - k.C()", Type: "int64"},
+		{Input: "not a == b and c", GoCode: "!(k.A() == k.B()) && k.C()", Type: "bool"},
+		{Input: "- -1", GoCode: "-(-1)", Type: "int64"},
+		{Input: "~a", GoCode: "^k.A()", Type: "int64"},
+		{Input: "1.5e-3 * 2", GoCode: "1.5e-3 * 2", Type: "float64"},
+		{Input: "0b1010 | 0x0F", GoCode: "10 | 0x0F", Type: "int64"},
+		{Input: `"a\tb" + 'c\d'`, GoCode: `"a\tb" + "c\\d"`, Type: "string"},
+		{Input: "a[b[0]].c", GoCode: "k.A()[k.B()[0]].C()", Type: "interface{}"},
+		{Input: "body.as<u4> + 1", GoCode: "int64(uint32(k.Body())) + 1", Type: "int64"},
+		{Input: "a.as<outer::inner>.b", GoCode: "k.A().(*Inner).B()", Type: "interface{}"},
+		{Input: "_parent._io.size", GoCode: "k.Parent().IO().Size()", Type: "int64"},
+		{Input: "_.len_data > _index", GoCode: "value.LenData() > index", Type: "bool"},
+		{
+			Input:  "a ? 1 : b ? 2 : 3",
+			GoCode: "func() int64 {\n\tif k.A() {\n\t\treturn 1\n\t}\n\treturn func() int64 {\n\t\tif k.B() {\n\t\t\treturn 2\n\t\t}\n\t\treturn 3\n\t}()\n}()",
+			Type:   "int64",
+		},
+		{
+			Input:  "(a ? 'x' : 'y') + 'z'",
+			GoCode: "func() string {\n\tif k.A() {\n\t\treturn \"x\"\n\t}\n\treturn \"y\"\n}() + \"z\"",
+			Type:   "string",
+		},
+	}
+
+	for _, result := range tests {
+		assert.EqualValues(t, result.GoCode, goExprAttr(result.Input, "", "value", ""), result.Input)
+		assert.EqualValues(t, result.Type, getType(result.Input, ""), result.Input)
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := map[string]string{
+		"(count +":     "column 9: expected operand, found end of expression",
+		"a ? b":        "column 6: expected ':', found end of expression",
+		"a b":          "column 3: unexpected 'b'",
+		"a.as<u4":      "column 8: expected '>', found end of expression",
+		"'unclosed":    "column 1: string literal not terminated",
+		`"\q"`:         `column 2: invalid escape \q`,
+		"0b12":         "column 1: invalid binary literal 0b12",
+		"12ab":         "column 1: invalid integer literal 12ab",
+		"a # b":        `column 3: unexpected character "#"`,
+		"f(1, 2":       "column 2: unexpected '('",
+		"x.foo(1, 2":   "column 11: expected ')', found end of expression",
+		"[1, 2":        "column 6: expected ']', found end of expression",
+		"animal::":     "column 9: expected enum name, found end of expression",
+		"a.5":          "column 3: expected name, found '5'",
+		"a == == b":    "column 6: expected operand, found '=='",
+		"not":          "column 4: expected operand, found end of expression",
+		"1 + (2 * 3))": "column 12: unexpected ')'",
+	}
+	for expr, msg := range tests {
+		_, err := parseExpr(expr)
+		if assert.Error(t, err, expr) {
+			assert.Equal(t, msg, err.Error(), expr)
+		}
+	}
+}
+
+const scopeSpec = `
+meta:
+  id: archive
+seq:
+  - id: num_files
+    type: u2
+  - id: name
+    type: str
+    size: 8
+  - id: files
+    type: file
+    repeat: expr
+    repeat-expr: num_files
+instances:
+  total:
+    value: num_files * 2
+  half:
+    value: total / 2.0
+  loop:
+    value: loop + 1
+types:
+  file:
+    seq:
+      - id: len_data
+        type: u4
+      - id: data
+        size: len_data
+        if: len_data
+      - id: tag
+        type: u1
+        repeat: until
+        repeat-until: _ == name
+    instances:
+      is_last:
+        value: _parent.num_files == _root.files.size
+      title:
+        value: _parent.name + '.bin'
+      missing:
+        value: _parent.unknown
+`
+
+func TestExprTypes(t *testing.T) {
+	var kaitai Type
+	if err := yaml.Unmarshal([]byte(scopeSpec), &kaitai); err != nil {
+		t.Fatal(err)
+	}
+	enumTypes = map[string]string{}
+	parents = map[string]string{}
+	typeParams = map[string][]Attribute{}
+	importTypes = map[string]string{}
+	typeScopes = map[string]*Type{}
+	setupMap(&kaitai, "archive")
+
+	tests := []struct{ Input, Scope, Type string }{
+		{"num_files", "Archive", "uint16"},
+		{"name", "Archive", "string"},
+		{"files", "Archive", "[]File"},
+		{"files[0].len_data + 1", "Archive", "int64"},
+		{"total", "Archive", "int64"},
+		{"half", "Archive", "float64"},
+		{"_parent.num_files", "File", "uint16"},
+		{"_root.files.first", "File", "File"},
+		{"_root.files[0].tag", "File", "[]uint8"},
+		{"_parent.name.length", "File", "int"},
+		{"title", "File", "string"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Type, getType(test.Input, test.Scope), test.Input)
+	}
+
+	// operands are widened to the type of the operation
+	codes := []struct{ Input, Scope, GoCode string }{
+		{"num_files + files[0].len_data", "Archive", "int64(k.NumFiles()) + int64(k.Files()[0].LenData())"},
+		{"num_files == files[0].len_data", "Archive", "int64(k.NumFiles()) == int64(k.Files()[0].LenData())"},
+		{"num_files + 1", "Archive", "int64(k.NumFiles()) + 1"},
+		{"half * num_files", "Archive", "k.Half() * float64(k.NumFiles())"},
+		{"-num_files", "Archive", "-int64(k.NumFiles())"},
+		{"len_data >> 4", "File", "int64(k.LenData()) >> 4"},
+		{"total % 13", "Archive", "runtime.Mod(k.Total(), 13)"},
+		{"half % (total - 1)", "Archive", "runtime.ModFloat(k.Half(), float64(k.Total()-1))"},
+		// byte arrays are compared lexicographically like strings
+		{"data == [1, 2]", "File", "bytes.Equal(k.Data(), []byte{1, 2})"},
+		{"not (data != [1])", "File", "!(!bytes.Equal(k.Data(), []byte{1}))"},
+		{"data >= [1] and data < (data + [2])", "File", "bytes.Compare(k.Data(), []byte{1}) >= 0 && bytes.Compare(k.Data(), bytes.Join([][]byte{k.Data(), []byte{2}}, nil)) < 0"},
+		{"_parent.name < 'b' or '_' + title == title", "File", `k.Parent().Name() < "b" || "_"+k.Title() == k.Title()`},
+	}
+	for _, test := range codes {
+		assert.Equal(t, test.GoCode, goExpr(test.Input, test.Scope), test.Input)
+	}
+
+	err := kaitai.Check("archive.ksy")
+	diags, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("expected diagnostics, got %v", err)
+	}
+	assert.Equal(t, Diagnostics{
+		{File: "archive.ksy", Line: 20, Column: 12, Msg: `value: invalid expression "loop + 1": type of "loop" in type Archive depends on itself`},
+		{File: "archive.ksy", Line: 28, Column: 13, Msg: `if: "len_data" must be a boolean, not uint32`},
+		{File: "archive.ksy", Line: 32, Column: 23, Msg: `repeat-until: invalid expression "_ == name": unknown attribute "name" in type File`},
+		{File: "archive.ksy", Line: 39, Column: 16, Msg: `value: invalid expression "_parent.unknown": unknown attribute "unknown" in type Archive`},
+	}, diags)
+}
+
+const methodSpec = `
+meta:
+  id: methods
+  encoding: UTF-8
+seq:
+  - id: nums
+    type: u4
+    repeat: expr
+    repeat-expr: 4
+  - id: floats
+    type: f8
+    repeat: eos
+  - id: name
+    type: str
+    size: 4
+  - id: raw
+    size: 4
+  - id: flag
+    type: b1
+  - id: ratio
+    type: f4
+  - id: big
+    type: u8
+  - id: delta
+    type: s2
+`
+
+func TestMethods(t *testing.T) {
+	var kaitai Type
+	if err := yaml.Unmarshal([]byte(methodSpec), &kaitai); err != nil {
+		t.Fatal(err)
+	}
+	enumTypes = map[string]string{}
+	parents = map[string]string{}
+	typeParams = map[string][]Attribute{}
+	importTypes = map[string]string{}
+	typeScopes = map[string]*Type{}
+	setupMap(&kaitai, "methods")
+
+	tests := []Result{
+		{Input: "nums.size", GoCode: "len(k.Nums())", Type: "int"},
+		{Input: "nums.first", GoCode: "runtime.First(k.Nums()).(uint32)", Type: "uint32"},
+		{Input: "nums.last", GoCode: "runtime.Last(k.Nums()).(uint32)", Type: "uint32"},
+		{Input: "nums.min", GoCode: "runtime.Min(k.Nums()).(uint32)", Type: "uint32"},
+		{Input: "floats.max", GoCode: "runtime.Max(k.Floats()).(float64)", Type: "float64"},
+		{Input: "name.length", GoCode: "utf8.RuneCountInString(k.Name())", Type: "int"},
+		{Input: "name.reverse", GoCode: "runtime.StringReverse(k.Name())", Type: "string"},
+		{Input: "name.substring(1, nums.first)", GoCode: "runtime.Substring(k.Name(), 1, int(runtime.First(k.Nums()).(uint32)))", Type: "string"},
+		{Input: "name.to_i", GoCode: "runtime.StringToInt(k.Name(), 10)", Type: "int64"},
+		{Input: "name.to_i(16)", GoCode: "runtime.StringToInt(k.Name(), 16)", Type: "int64"},
+		{Input: "raw.to_s('UTF-16LE')", GoCode: `runtime.BytesToString(k.Raw(), "UTF-16LE")`, Type: "string"},
+		{Input: "ratio.to_i", GoCode: "int64(k.Ratio())", Type: "int64"},
+		{Input: "flag.to_i", GoCode: "runtime.BoolToInt(k.Flag())", Type: "int64"},
+		{Input: "nums.first.to_s", GoCode: "strconv.FormatUint(uint64(runtime.First(k.Nums()).(uint32)), 10)", Type: "string"},
+		{Input: "big.to_s", GoCode: "strconv.FormatUint(k.Big(), 10)", Type: "string"},
+		{Input: "delta.to_s", GoCode: "strconv.FormatInt(int64(k.Delta()), 10)", Type: "string"},
+		{Input: "(big + 1).to_s", GoCode: "strconv.FormatUint(k.Big()+1, 10)", Type: "string"},
+	}
+	for _, result := range tests {
+		assert.EqualValues(t, result.GoCode, goExpr(result.Input, "Methods"), result.Input)
+		assert.EqualValues(t, result.Type, getType(result.Input, "Methods"), result.Input)
+	}
+
+	errors := map[string]string{
+		"name.substring(1)":  "substring takes 2 arguments, not 1",
+		"name.to_i('x')":     "argument of to_i of type string",
+		"nums.size()":        "size is not a method call",
+		"raw.to_s":           "to_s of bytes takes an encoding",
+		"raw.to_s(name)":     "encoding of to_s must be a string literal",
+		"raw.to_s('EBCDIC')": `unknown encoding "EBCDIC"`,
+		"ratio.to_s":         `unknown method "to_s" of type float32`,
+		"name.min":           `unknown method "min" of type string`,
+	}
+	for expr, msg := range errors {
+		_, err := checkExpr(expr, "Methods", "")
+		if assert.Error(t, err, expr) {
+			assert.Equal(t, "invalid expression "+strconv.Quote(expr)+": "+msg, err.Error(), expr)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-ee/kaitaigo/runtime"
+)
+
+// Types of expressions are Go types. Integer arithmetic is done in int64,
+// or uint64 if an operand is an uint64, and floating point arithmetic in
+// float64, like Kaitai calculates with the widest type. Operands of other
+// types are converted, so u1 + u4 can not overflow. An empty type is
+// unknown, e.g. a field of an imported type, and is accepted by every
+// operator.
+
+func isIntType(t string) bool {
+	switch t {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+		return true
+	}
+	return false
+}
+
+func isFloatType(t string) bool {
+	return t == "float32" || t == "float64"
+}
+
+func isNumericType(t string) bool {
+	return isIntType(t) || isFloatType(t)
+}
+
+// elemType returns the element type of a slice or array type like []T or
+// [4]T, or an empty string for other types.
+func elemType(t string) string {
+	if !strings.HasPrefix(t, "[") {
+		return ""
+	}
+	return t[strings.Index(t, "]")+1:]
+}
+
+// promote returns the type of arithmetic on x and y.
+func promote(x, y string) string {
+	switch {
+	case isFloatType(x) || isFloatType(y):
+		return "float64"
+	case x == "uint64" || y == "uint64":
+		return "uint64"
+	}
+	return "int64"
+}
+
+func (c *exprContext) errorf(format string, args ...interface{}) {
+	c.fail(fmt.Errorf(format, args...))
+}
+
+// fail records err, if it is the first error of the expression.
+func (c *exprContext) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// typeOf returns the type of n. Type errors are recorded in c.err.
+func (c *exprContext) typeOf(n exprNode) string {
+	switch n := n.(type) {
+	case *intLit:
+		return "int64"
+	case *floatLit:
+		return "float64"
+	case *strLit:
+		return "string"
+	case *boolLit:
+		return "bool"
+	case *arrayLit:
+		for _, elem := range n.Elems {
+			if t := c.typeOf(elem); t != "" && !isIntType(t) {
+				c.errorf("byte array element of type %s", t)
+			}
+		}
+		return "[]byte"
+	case *enumLit:
+		return getEnumType(strings.Join(n.Path[:len(n.Path)-1], "::"))
+	case *ident:
+		return c.identType(n.Name)
+	case *member:
+		return c.memberType(n)
+	case *indexExpr:
+		if t := c.typeOf(n.Index); t != "" && !isIntType(t) {
+			c.errorf("index of type %s", t)
+		}
+		t := c.typeOf(n.X)
+		if elem := elemType(t); elem != "" {
+			return elem
+		}
+		if t != "" {
+			c.errorf("index of non-array type %s", t)
+		}
+		return ""
+	case *castExpr:
+		c.typeOf(n.X)
+		if goType, ok := typeMapping[n.Type]; ok {
+			return goType
+		}
+		key := TypeKey{Type: n.Type[strings.LastIndex(n.Type, ":")+1:]}
+		return "*" + key.String()
+	case *unaryExpr:
+		t := c.typeOf(n.X)
+		switch {
+		case t == "":
+		case n.Op == "not" && t != "bool":
+			c.errorf("not of type %s", t)
+		case n.Op == "-" && !isNumericType(t):
+			c.errorf("negation of type %s", t)
+		case n.Op == "~" && !isIntType(t):
+			c.errorf("bitwise not of type %s", t)
+		}
+		switch {
+		case n.Op == "not":
+			return "bool"
+		case n.Op == "~" && t == "uint64":
+			return t
+		}
+		return promote(t, "")
+	case *binaryExpr:
+		return c.binaryType(n)
+	case *ternaryExpr:
+		if t := c.typeOf(n.Cond); t != "" && t != "bool" {
+			c.errorf("condition of type %s", t)
+		}
+		then, els := c.typeOf(n.Then), c.typeOf(n.Else)
+		switch {
+		case then == els || els == "":
+			return then
+		case then == "":
+			return els
+		case isNumericType(then) && isNumericType(els):
+			return promote(then, els)
+		}
+		c.errorf("branches of different types %s and %s", then, els)
+		return ""
+	case *parenExpr:
+		return c.typeOf(n.X)
+	}
+	return ""
+}
+
+// identType returns the type of a name at the start of an expression.
+func (c *exprContext) identType(name string) string {
+	switch name {
+	case "_io":
+		return "runtime.IO"
+	case "_index":
+		return "int"
+	case "_":
+		return c.currentType
+	}
+	if c.scope == nil {
+		return ""
+	}
+	switch name {
+	case "_root":
+		return "*" + c.scope.root
+	case "_parent":
+		return c.scope.parentType()
+	}
+	t, err := c.scope.fieldType(name)
+	if err != nil {
+		c.fail(err)
+	}
+	return t
+}
+
+// ioTypes are the result types of the methods of runtime.IO.
+var ioTypes = map[string]string{
+	"pos":  "int64",
+	"size": "int64",
+	"eof":  "bool",
+}
+
+// memberType returns the type of a field or method of a value.
+func (c *exprContext) memberType(n *member) string {
+	t := c.typeOf(n.X)
+	for _, arg := range n.Args {
+		c.typeOf(arg)
+	}
+	if t == "runtime.IO" {
+		if ioType, ok := ioTypes[n.Name]; ok && !n.Call {
+			return ioType
+		}
+		c.errorf("unknown method %q of a stream", n.Name)
+		return ""
+	}
+	if n.Name == "_io" {
+		return "runtime.IO"
+	}
+	if scope := getTypeScope(t); scope != nil {
+		switch n.Name {
+		case "_root":
+			return "*" + scope.root
+		case "_parent":
+			return scope.parentType()
+		}
+		fieldType, err := scope.fieldType(n.Name)
+		if err != nil {
+			c.fail(err)
+		}
+		return fieldType
+	}
+	if t == "" {
+		// the result types of the built-in methods are known anyway
+		switch n.Name {
+		case "to_i":
+			return "int64"
+		case "length", "size":
+			return "int"
+		case "to_s", "reverse", "substring":
+			return "string"
+		}
+		return ""
+	}
+	elem := elemType(t)
+	switch {
+	case n.Name == "to_i" && t == "string":
+		c.intArgs(n, 0, 1)
+		return "int64"
+	case n.Name == "to_i" && (isNumericType(t) || isEnumType(t) || t == "bool"):
+		c.intArgs(n, 0, 0)
+		return "int64"
+	case n.Name == "to_s" && isIntType(t):
+		c.intArgs(n, 0, 0)
+		return "string"
+	case n.Name == "to_s" && t == "[]byte":
+		c.encodingArg(n)
+		return "string"
+	case (n.Name == "length" || n.Name == "reverse") && t == "string":
+		c.intArgs(n, 0, 0)
+		if n.Name == "reverse" {
+			return "string"
+		}
+		return "int"
+	case n.Name == "substring" && t == "string":
+		c.intArgs(n, 2, 2)
+		return "string"
+	case n.Name == "size" && elem != "":
+		c.intArgs(n, 0, 0)
+		return "int"
+	case (n.Name == "first" || n.Name == "last") && elem != "":
+		c.intArgs(n, 0, 0)
+		return elem
+	case (n.Name == "min" || n.Name == "max") && elem != "":
+		c.intArgs(n, 0, 0)
+		if !isNumericType(elem) && elem != "string" && elem != "[]byte" {
+			c.errorf("%s of array of type %s", n.Name, elem)
+		}
+		return elem
+	}
+	if strings.HasPrefix(t, "*") || strings.HasPrefix(t, "runtime.") || t == "interface{}" {
+		// imported or unknown user type
+		return ""
+	}
+	c.errorf("unknown method %q of type %s", n.Name, t)
+	return ""
+}
+
+// intArgs checks, that a built-in method is called with min to max integer
+// arguments.
+func (c *exprContext) intArgs(n *member, min, max int) {
+	switch {
+	case len(n.Args) < min || len(n.Args) > max:
+		want := strconv.Itoa(min)
+		if min != max {
+			want += " to " + strconv.Itoa(max)
+		}
+		c.errorf("%s takes %s arguments, not %d", n.Name, want, len(n.Args))
+	case n.Call && max == 0:
+		c.errorf("%s is not a method call", n.Name)
+	}
+	for _, arg := range n.Args {
+		if t := c.typeOf(arg); t != "" && !isIntType(t) {
+			c.errorf("argument of %s of type %s", n.Name, t)
+		}
+	}
+}
+
+// encodingArg checks the encoding of bytes.to_s(encoding), which must be a
+// known encoding.
+func (c *exprContext) encodingArg(n *member) {
+	if len(n.Args) != 1 {
+		c.errorf("to_s of bytes takes an encoding")
+		return
+	}
+	if encoding, ok := n.Args[0].(*strLit); !ok {
+		c.errorf("encoding of to_s must be a string literal")
+	} else if !runtime.IsKnownEncoding(encoding.Value) {
+		c.errorf("unknown encoding %q", encoding.Value)
+	}
+}
+
+// binaryType returns the type of a binary operation and checks the types of
+// the operands.
+func (c *exprContext) binaryType(n *binaryExpr) string {
+	x, y := c.typeOf(n.X), c.typeOf(n.Y)
+	unknown := x == "" || y == ""
+	both := func(is func(string) bool) bool {
+		return (x == "" || is(x)) && (y == "" || is(y))
+	}
+	isBool := func(t string) bool { return t == "bool" }
+	isString := func(t string) bool { return t == "string" }
+	isBytes := func(t string) bool { return t == "[]byte" }
+
+	switch n.Op {
+	case "and", "or":
+		if both(isBool) {
+			return "bool"
+		}
+	case "==", "!=":
+		if unknown || x == y || both(isNumericType) {
+			return "bool"
+		}
+	case "<", "<=", ">", ">=":
+		if both(isNumericType) || both(isString) || both(isBytes) {
+			return "bool"
+		}
+	case "+":
+		switch {
+		case both(isString) && (x == "string" || y == "string"):
+			return "string"
+		case both(isBytes) && (x == "[]byte" || y == "[]byte"):
+			return "[]byte"
+		case both(isNumericType):
+			return promote(x, y)
+		}
+	case "-", "*", "/", "%":
+		if both(isNumericType) {
+			return promote(x, y)
+		}
+	case "<<", ">>":
+		// >> is arithmetic on signed and logical on unsigned integers
+		if both(isIntType) {
+			return promote(x, "")
+		}
+	case "&", "|", "^":
+		switch {
+		case both(isBool) && (x == "bool" || y == "bool"):
+			return "bool"
+		case both(isIntType):
+			return promote(x, y)
+		}
+	}
+	c.errorf("operator %s not defined on %s and %s", n.Op, typeName(x), typeName(y))
+	return ""
+}
+
+func typeName(t string) string {
+	if t == "" {
+		return "unknown type"
+	}
+	return t
+}
@@ -1,87 +0,0 @@
-package main
-
-import "go/token"
-
-var expressionTypes = map[token.Token]string{
-	token.ILLEGAL:        "panic",
-	token.EOF:            "panic",
-	token.COMMENT:        "panic",
-	token.IDENT:          "XXX",
-	token.INT:            "int",
-	token.FLOAT:          "float",
-	token.IMAG:           "panic",
-	token.CHAR:           "string",
-	token.STRING:         "string",
-	token.ADD:            "XXX",
-	token.SUB:            "int",
-	token.MUL:            "int",
-	token.QUO:            "int",
-	token.REM:            "int",
-	token.AND:            "int",
-	token.OR:             "int",
-	token.XOR:            "int",
-	token.SHL:            "int",
-	token.SHR:            "int",
-	token.AND_NOT:        "int",
-	token.ADD_ASSIGN:     "XXX",
-	token.SUB_ASSIGN:     "int",
-	token.MUL_ASSIGN:     "int",
-	token.QUO_ASSIGN:     "int",
-	token.REM_ASSIGN:     "int",
-	token.AND_ASSIGN:     "bool",
-	token.OR_ASSIGN:      "bool",
-	token.XOR_ASSIGN:     "int",
-	token.SHL_ASSIGN:     "int",
-	token.SHR_ASSIGN:     "int",
-	token.AND_NOT_ASSIGN: "int",
-	token.LAND:           "bool",
-	token.LOR:            "bool",
-	token.ARROW:          "panic",
-	token.INC:            "int",
-	token.DEC:            "int",
-	token.EQL:            "bool",
-	token.LSS:            "bool",
-	token.GTR:            "bool",
-	token.ASSIGN:         "panic",
-	token.NOT:            "bool",
-	token.NEQ:            "bool",
-	token.LEQ:            "bool",
-	token.GEQ:            "bool",
-	token.DEFINE:         "panic",
-	token.ELLIPSIS:       "panic",
-	token.LPAREN:         "panic",
-	token.LBRACK:         "panic",
-	token.LBRACE:         "panic",
-	token.COMMA:          "panic",
-	token.PERIOD:         "panic",
-	token.RPAREN:         "panic",
-	token.RBRACK:         "panic",
-	token.RBRACE:         "panic",
-	token.SEMICOLON:      "panic",
-	token.COLON:          "panic",
-	token.BREAK:          "panic",
-	token.CASE:           "panic",
-	token.CHAN:           "panic",
-	token.CONST:          "panic",
-	token.CONTINUE:       "panic",
-	token.DEFAULT:        "panic",
-	token.DEFER:          "panic",
-	token.ELSE:           "panic",
-	token.FALLTHROUGH:    "panic",
-	token.FOR:            "panic",
-	token.FUNC:           "panic",
-	token.GO:             "panic",
-	token.GOTO:           "panic",
-	token.IF:             "panic",
-	token.IMPORT:         "panic",
-	token.INTERFACE:      "panic",
-	token.MAP:            "panic",
-	token.PACKAGE:        "panic",
-	token.RANGE:          "panic",
-	token.RETURN:         "panic",
-	token.SELECT:         "panic",
-	token.STRUCT:         "panic",
-	token.SWITCH:         "panic",
-	token.TYPE:           "panic",
-	token.VAR:            "panic",
-}
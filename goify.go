@@ -1,34 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
-	"go/ast"
 	"go/format"
 	"go/parser"
 	"go/token"
-	"log"
-	"regexp"
 	"strconv"
 	"strings"
-	"text/scanner"
 
 	"github.com/iancoleman/strcase"
 )
 
 var typeMapping = map[string]string{
-	"b1":   "bool",
-	"b1le": "bool",
-	"b1be": "bool",
-	"b2":   "uint",
-	"b2le": "uint",
-	"b2be": "uint",
-	"b4le": "uint64",
-	"b6le": "uint64",
-	"b3":   "uint64",
-	"b4":   "uint64",
-	"b5":   "uint64",
-	"b6":   "uint64",
-	"b7":   "uint64",
 	"u1":   "uint8",
 	"u2":   "uint16",
 	"u4":   "uint32",
@@ -60,293 +44,467 @@ var typeMapping = map[string]string{
 	"":     "[]byte",
 }
 
-func bitString(s string) string {
-	num, err := strconv.ParseInt(s[2:], 2, 64)
-	if err != nil {
-		panic(err)
+func init() {
+	// bit sized integers b1 to b64, single bits are flags
+	for n := 1; n <= 64; n++ {
+		dataType := "uint64"
+		if n == 1 {
+			dataType = "bool"
+		}
+		for _, endian := range []string{"", "be", "le"} {
+			typeMapping[fmt.Sprintf("b%d%s", n, endian)] = dataType
+		}
 	}
-	return fmt.Sprintf("%d", num)
+}
+
+// splitArgs splits a comma separated argument list, ignoring commas inside
+// brackets and string literals.
+func splitArgs(s string) (args []string) {
+	depth := 0
+	var quote rune
+	start := 0
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" || len(args) > 0 {
+		args = append(args, rest)
+	}
+	return
 }
 
 func isInt(expr string) bool {
 	return !strings.Contains(goExpr(expr, ""), "k.")
 }
 
-func getExprType(expr ast.Expr) (s string, r bool) {
-	// fmt.Printf("%#v\n", expr)
-	ast.Inspect(expr, func(n ast.Node) bool {
-		switch x := n.(type) {
-		case *ast.BasicLit:
-			s = expressionTypes[x.Kind]
-			return false
-		case *ast.Ident:
-			if x.Name == "true" || x.Name == "false" {
-				s = "bool"
-			} else {
-				if isNative(x.Name) {
-					s = x.Name
-				} else {
-					s = getKaitaiType(x.Name)
-				}
-			}
-			return false
-		case *ast.UnaryExpr:
-			if x.Op == token.NOT {
-				s = "bool"
-			} else {
-				s, r = getExprType(x.X)
-			}
-			return false
-		case *ast.BinaryExpr:
-			s = expressionTypes[x.Op]
-			return x.Op == token.ADD || x.Op == token.ADD_ASSIGN
-		case *ast.CallExpr:
-			s, r = getExprType(x.Fun)
-			return r
-		case *ast.SelectorExpr:
-			s, r = getExprType(x.Sel)
-			return r
-		case *ast.FuncType:
-			s, r = getExprType(x.Results.List[0].Type)
-			return r
-		default:
-			return true
-		}
-	})
-	return
+// goPrec is the precedence of the Go binary operators. Unary expressions
+// bind stronger, operands strongest.
+var goPrec = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3,
+	"+": 4, "-": 4, "|": 4, "^": 4,
+	"*": 5, "/": 5, "%": 5, "<<": 5, ">>": 5, "&": 5,
+}
+
+const (
+	unaryPrec   = 6
+	operandPrec = 7
+)
+
+// goOps are the Go operators of Kaitai operators with another spelling.
+var goOps = map[string]string{
+	"and": "&&",
+	"or":  "||",
+	"not": "!",
+	"~":   "^",
 }
 
-func getType(expr string) (t string) {
-	var re = regexp.MustCompile(`\*k.*\(\)`)
-	goExpr := re.ReplaceAllString(goExpr(expr, ""), `"x"`)
+// exprContext translates the syntax tree of an expression to Go code and
+// infers the types of its nodes. Identifiers are resolved in scope, _ is
+// translated to current of type currentType.
+type exprContext struct {
+	scope       *Type
+	current     string
+	currentType string
+	// first type error
+	err error
+}
 
-	// fmt.Println()
-	// fmt.Println(goExpr)
+func newExprContext(scope, current, currentType string) *exprContext {
+	return &exprContext{scope: getTypeScope(scope), current: current, currentType: currentType}
+}
 
-	exprx, _ := parser.ParseExpr(goExpr)
-	var s string
-	if exprx != nil {
-		s, _ = getExprType(exprx)
+func goOp(op string) string {
+	if goOp, ok := goOps[op]; ok {
+		return goOp
 	}
-	// fmt.Println("s", s)
-	switch s {
-	case "int":
-		return "int64"
-	case "string":
-		return "[]byte"
-	case "bool":
-		return "bool"
-	case "":
-		return "[]byte"
-	case "float":
-		return "float64"
-	default:
-		return s
+	return op
+}
+
+func paren(code string, prec, min int) string {
+	if prec < min {
+		return "(" + code + ")"
 	}
+	return code
 }
 
-func goenum(s string, cast string) string {
-	// cast
-	if strings.HasSuffix(s, ".to_i") {
-		s = s[:len(s)-5]
-		cast = "int64"
+// isConst reports whether n is a numeric constant. Go converts untyped
+// constants implicitly.
+func isConst(n exprNode) bool {
+	switch n := n.(type) {
+	case *intLit, *floatLit:
+		return true
+	case *unaryExpr:
+		return n.Op != "not" && isConst(n.X)
+	case *parenExpr:
+		return isConst(n.X)
+	case *binaryExpr:
+		return goPrec[goOp(n.Op)] >= goPrec["+"] && isConst(n.X) && isConst(n.Y)
 	}
+	return false
+}
 
-	parts := strings.SplitN(s, "::", 2)
-	if len(parts) < 2 {
-		return s
+// convert converts the Go code of n with precedence prec to goType, if n
+// has another numeric type.
+func (c *exprContext) convert(code string, prec int, n exprNode, goType string) (string, int) {
+	if isConst(n) {
+		return code, prec
 	}
-	s = strcase.ToCamel(parts[0]) + "." + strcase.ToCamel(parts[1])
-	if cast != "" {
-		return cast + "(" + s + ")"
+	if t := c.typeOf(n); t != goType && isNumericType(t) && isNumericType(goType) {
+		return goType + "(" + code + ")", operandPrec
 	}
+	return code, prec
+}
 
-	return s
+// operandType returns the type the operands of n are converted to, or an
+// empty string if they are used as they are.
+func (c *exprContext) operandType(n *binaryExpr) string {
+	x, y := c.typeOf(n.X), c.typeOf(n.Y)
+	if !isNumericType(x) || !isNumericType(y) {
+		return ""
+	}
+	switch n.Op {
+	case "and", "or":
+		return ""
+	case "<<", ">>":
+		return promote(x, "")
+	}
+	return promote(x, y)
 }
 
-func isIdentifierPart(tok rune, casting bool) bool {
-	// handle greater and lower than
-	if tok == '<' || tok == '>' {
-		if !casting {
-			return false
-		} else {
-			return true
+// mod returns the Go code of n, a modulo with the sign of the divisor like
+// in Kaitai. Go's % has the sign of the dividend.
+func (c *exprContext) mod(n *binaryExpr, goType string) (string, int) {
+	if isFloatType(goType) || (isIntType(goType) && goType != "uint64" && !isConst(n)) {
+		x, _ := c.code(unparen(n.X))
+		y, _ := c.code(unparen(n.Y))
+		x, _ = c.convert(x, operandPrec, n.X, goType)
+		y, _ = c.convert(y, operandPrec, n.Y, goType)
+		if isFloatType(goType) {
+			return "runtime.ModFloat(" + x + ", " + y + ")", operandPrec
 		}
+		return "runtime.Mod(" + x + ", " + y + ")", operandPrec
+	}
+	x, xPrec := c.code(n.X)
+	y, yPrec := c.code(n.Y)
+	x, xPrec = c.convert(x, xPrec, n.X, goType)
+	y, yPrec = c.convert(y, yPrec, n.Y, goType)
+	x, y = paren(x, xPrec, goPrec["%"]), paren(y, yPrec, goPrec["%"]+1)
+	if isConst(n) {
+		// stays a constant, e.g. for array sizes
+		return fmt.Sprintf("(%s%%%s + %s) %% %s", x, y, y, y), goPrec["%"]
 	}
-	return tok == scanner.Ident || tok == '.' || tok == '"' || tok == '_' || tok == '[' || tok == ']' || tok == '\''
+	// unsigned or unknown types
+	return x + " % " + y, goPrec["%"]
 }
 
-func goExprIdent(expr, castType, currentAttr string) string {
-	ret := "k."
-	var s scanner.Scanner
-	s.Init(strings.NewReader(expr))
-	s.Filename = "example"
-	cast := false
-	start := true
+// isBytesOp reports whether n operates on byte arrays, which Go can not
+// compare or add with operators.
+func (c *exprContext) isBytesOp(n *binaryExpr) bool {
+	x, y := c.typeOf(n.X), c.typeOf(n.Y)
+	if x != "[]byte" && y != "[]byte" {
+		return false
+	}
+	return (x == "[]byte" || x == "") && (y == "[]byte" || y == "")
+}
 
-	exprTrimmed := strings.Trim(expr, " ")
+// bytesOp returns the Go code of a comparison or concatenation of byte
+// arrays. Byte arrays are compared lexicographically like strings.
+func (c *exprContext) bytesOp(n *binaryExpr) (string, int) {
+	x, _ := c.code(unparen(n.X))
+	y, _ := c.code(unparen(n.Y))
+	switch n.Op {
+	case "==":
+		return "bytes.Equal(" + x + ", " + y + ")", operandPrec
+	case "!=":
+		return "!bytes.Equal(" + x + ", " + y + ")", unaryPrec
+	case "+":
+		return "bytes.Join([][]byte{" + x + ", " + y + "}, nil)", operandPrec
+	}
+	return "bytes.Compare(" + x + ", " + y + ") " + n.Op + " 0", goPrec[n.Op]
+}
 
-	// fmt.Println(expr)
-	for tok := s.Scan(); tok != scanner.EOF; tok = s.Scan() {
-		// fmt.Println("inner", s.TokenText())
-		switch s.TokenText() {
-		case "not":
-			ret = "!"
-		case "true", "false":
-			ret = s.TokenText()
-		case "_":
-			ret = ret[:len(ret)-1] + "." + currentAttr
-		case "\"":
-			ret += "\""
-		case ".":
-			ret += "."
-		case "<":
-			ret += "("
-		case ">":
-			ret += ")"
-			cast = false
-		case "[":
-			if start {
-				ret = "[]byte{"
-			} else {
-				ret += s.TokenText()
-			}
-		case "]":
-			if start {
-				ret = "}"
-			} else {
-				ret += s.TokenText()
-			}
-		case "_parent":
-			ret += "ParentBase()"
-		case "_root":
-			ret += "RootBase()"
-		case "_index":
-			ret += "index"
-		case "to_i":
-			ret = "int64(" + ret[:len(ret)-1] + ")"
-		case "to_s":
-			ret = "strconv.Itoa(int(" + ret[:len(ret)-1] + "))"
-		case "as":
-			cast = true
-		case "first":
-			if exprTrimmed == "first" {
-				ret += strcase.ToCamel(s.TokenText())
-				if !cast {
-					ret += "()"
-				}
-			} else {
-				ret = ret[:len(ret)-1] + "[0]"
-			}
-		case "last":
-			if exprTrimmed == "last" {
-				ret += strcase.ToCamel(s.TokenText())
-				if !cast {
-					ret += "()"
-				}
-			} else {
-				ret = ret[:len(ret)-1] + "[:len(" + ret[:len(ret)-1] + ")-1]"
-			}
-		case "length":
-			if exprTrimmed == "length" {
-				ret += strcase.ToCamel(s.TokenText())
-				if !cast {
-					ret += "()"
-				}
-			} else {
-				ret = "len(" + ret[:len(ret)-1] + ")"
-			}
-		case "size":
-			if exprTrimmed == "size" {
-				ret += strcase.ToCamel(s.TokenText())
-				if !cast {
-					ret += "()"
-				}
-			} else {
-				ret = "len(" + ret[:len(ret)-1] + ")"
-			}
-		default:
-			ret += strcase.ToCamel(s.TokenText())
-			if !cast {
-				ret += "()"
-			}
+func unparen(n exprNode) exprNode {
+	for {
+		p, ok := n.(*parenExpr)
+		if !ok {
+			return n
 		}
-		start = false
+		n = p.X
 	}
+}
 
-	if castType != "" {
-		return castType + "(" + ret + ")"
+// code returns the Go code of n and its precedence.
+func (c *exprContext) code(n exprNode) (string, int) {
+	switch n := n.(type) {
+	case *intLit:
+		return n.Text, operandPrec
+	case *floatLit:
+		return n.Text, operandPrec
+	case *strLit:
+		return strconv.Quote(n.Value), operandPrec
+	case *boolLit:
+		return strconv.FormatBool(n.Value), operandPrec
+	case *arrayLit:
+		elems := make([]string, len(n.Elems))
+		for i, elem := range n.Elems {
+			elems[i], _ = c.code(elem)
+		}
+		return "[]byte{" + strings.Join(elems, ", ") + "}", operandPrec
+	case *enumLit:
+		last := len(n.Path) - 1
+		return getEnumType(strings.Join(n.Path[:last], "::")) + strcase.ToCamel(n.Path[last]), operandPrec
+	case *ident:
+		return c.ident(n.Name), operandPrec
+	case *member:
+		return c.member(n), operandPrec
+	case *indexExpr:
+		x, prec := c.code(n.X)
+		index, _ := c.code(n.Index)
+		return paren(x, prec, operandPrec) + "[" + index + "]", operandPrec
+	case *castExpr:
+		x, prec := c.code(n.X)
+		goType := c.typeOf(n)
+		switch {
+		case !strings.HasPrefix(goType, "*"):
+			return goType + "(" + x + ")", operandPrec
+		case c.typeOf(n.X) == goType:
+			return x, prec
+		}
+		return paren(x, prec, operandPrec) + ".(" + goType + ")", operandPrec
+	case *unaryExpr:
+		x, prec := c.code(n.X)
+		if n.Op != "not" {
+			x, prec = c.convert(x, prec, n.X, c.typeOf(n))
+		}
+		op := goOp(n.Op)
+		if strings.HasPrefix(x, op) {
+			// --x is a decrement in Go
+			prec = 0
+		}
+		return op + paren(x, prec, unaryPrec), unaryPrec
+	case *binaryExpr:
+		op := goOp(n.Op)
+		if c.isBytesOp(n) {
+			return c.bytesOp(n)
+		}
+		goType := c.operandType(n)
+		if op == "%" {
+			return c.mod(n, goType)
+		}
+		x, xPrec := c.code(n.X)
+		y, yPrec := c.code(n.Y)
+		x, xPrec = c.convert(x, xPrec, n.X, goType)
+		if op != "<<" && op != ">>" {
+			y, yPrec = c.convert(y, yPrec, n.Y, goType)
+		}
+		// Kaitai and Go differ in precedence, operators are left associative
+		return paren(x, xPrec, goPrec[op]) + " " + op + " " + paren(y, yPrec, goPrec[op]+1), goPrec[op]
+	case *ternaryExpr:
+		goType := c.typeOf(n)
+		if goType == "" {
+			goType = "interface{}"
+		}
+		cond, _ := c.code(n.Cond)
+		then, _ := c.code(n.Then)
+		els, _ := c.code(n.Else)
+		then, _ = c.convert(then, operandPrec, n.Then, goType)
+		els, _ = c.convert(els, operandPrec, n.Else, goType)
+		return fmt.Sprintf("func() %s { if %s { return %s }; return %s }()", goType, cond, then, els), operandPrec
+	case *parenExpr:
+		x, prec := c.code(n.X)
+		if prec == operandPrec {
+			return x, prec
+		}
+		return "(" + x + ")", operandPrec
 	}
-	return ret
-
+	panic(fmt.Sprintf("unknown expression node %T", n))
 }
 
-func goExpr(expr, castType string) string {
-	return goExprAttr(expr, castType, "")
+// ident translates a name at the start of an expression.
+func (c *exprContext) ident(name string) string {
+	switch name {
+	case "_":
+		return c.current
+	case "_index":
+		return "index"
+	}
+	return "k." + goMember(name)
 }
 
-func goExprAttr(expr, castType, currentAttr string) string {
-	// replace binary values
-	re := regexp.MustCompile("0[bB][0-9]+")
-	expr = re.ReplaceAllStringFunc(expr, bitString)
-
-	re = regexp.MustCompile("'")
-	expr = re.ReplaceAllString(expr, "\"")
-
-	var s scanner.Scanner
-	s.Init(strings.NewReader(expr))
-	s.Whitespace = 0
-	s.Filename = "example"
-	identifier := ""
-	casting := false
-	ret := ""
-	for tok := s.Scan(); tok != scanner.EOF; tok = s.Scan() {
-		// fmt.Println(tok, s.TokenText())
-
-		// handle identifier chain
-		if !isIdentifierPart(tok, casting) && identifier != "" {
-			ret += " " + goExprIdent(identifier, castType, currentAttr)
-			identifier = ""
-		}
+// goMember returns the getter call of an attribute or special name.
+func goMember(name string) string {
+	switch name {
+	case "_io":
+		return "IO()"
+	case "_root":
+		return "Root()"
+	case "_parent":
+		return "Parent()"
+	}
+	return strcase.ToCamel(name) + "()"
+}
 
+// member translates a field access or method call. Built-in methods are
+// translated to Go builtins or runtime helpers.
+func (c *exprContext) member(n *member) string {
+	x, prec := c.code(n.X)
+	x = paren(x, prec, operandPrec)
+	t := c.typeOf(n.X)
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i], _ = c.code(arg)
+	}
+	// intArg is argument i converted to an int index or radix
+	intArg := func(i int) string {
+		arg, _ := c.convert(args[i], operandPrec, n.Args[i], "int")
+		return arg
+	}
+	switch n.Name {
+	case "to_i":
 		switch {
-		case isIdentifierPart(tok, casting):
-
-			identifierPart := s.TokenText()
-			// identify casting start
-			if identifierPart == "as" {
-				casting = true
-			}
-			// identify casting end
-			if tok == '>' {
-				casting = false
+		case t == "string":
+			radix := "10"
+			if len(args) == 1 {
+				radix = intArg(0)
 			}
-
-			identifier += " " + identifierPart
-		case tok == '?':
-			parts := strings.SplitN(expr, "?", 2)
-			check := goExpr(parts[0], "")
-			cases := strings.SplitN(parts[1], ":", 2)
-			ifvalue := goExpr(cases[0], "")
-			elsevalue := goExpr(cases[1], "")
-
-			return fmt.Sprintf("func()"+getType(ifvalue)+"{if %s{return %s}else{return %s}}()", check, ifvalue, elsevalue)
-		default:
-			ret += s.TokenText()
+			return "runtime.StringToInt(" + x + ", " + radix + ")"
+		case t == "bool":
+			return "runtime.BoolToInt(" + x + ")"
+		}
+		return "int64(" + x + ")"
+	case "to_s":
+		if t == "[]byte" && len(args) == 1 {
+			return "runtime.BytesToString(" + x + ", " + args[0] + ")"
+		}
+		// format uint64 without truncation
+		arg, _ := c.code(unparen(n.X))
+		if strings.HasPrefix(t, "uint") || t == "byte" {
+			arg, _ = c.convert(arg, operandPrec, n.X, "uint64")
+			return "strconv.FormatUint(" + arg + ", 10)"
+		}
+		if t == "" {
+			return "strconv.FormatInt(int64(" + arg + "), 10)"
+		}
+		arg, _ = c.convert(arg, operandPrec, n.X, "int64")
+		return "strconv.FormatInt(" + arg + ", 10)"
+	case "first", "last", "min", "max":
+		// the runtime helpers fail on empty arrays
+		if elem := elemType(t); elem != "" {
+			return "runtime." + strcase.ToCamel(n.Name) + "(" + x + ").(" + elem + ")"
+		}
+		switch n.Name {
+		case "first":
+			return x + "[0]"
+		case "last":
+			return x + "[len(" + x + ")-1]"
+		}
+	case "length":
+		if t == "string" {
+			// in characters, not bytes
+			return "utf8.RuneCountInString(" + x + ")"
 		}
+		return "len(" + x + ")"
+	case "reverse":
+		if t == "string" {
+			return "runtime.StringReverse(" + x + ")"
+		}
+	case "substring":
+		if t == "string" && len(args) == 2 {
+			return "runtime.Substring(" + x + ", " + intArg(0) + ", " + intArg(1) + ")"
+		}
+	case "size":
+		if t != "runtime.IO" {
+			return "len(" + x + ")"
+		}
+	case "eof":
+		if t == "runtime.IO" {
+			return x + ".EOF()"
+		}
+	}
+	if n.Call {
+		return x + "." + strcase.ToCamel(n.Name) + "(" + strings.Join(args, ", ") + ")"
 	}
+	return x + "." + goMember(n.Name)
+}
 
-	// handle identifier chain
-	if identifier != "" {
-		ret += " " + goExprIdent(identifier, castType, currentAttr)
+// getType returns the Go type of the expression in the user type scope.
+// Expressions of unknown type are interface{}.
+func getType(expr, scope string) string {
+	n, err := parseExpr(expr)
+	if err != nil {
+		return "interface{}"
+	}
+	if t := newExprContext(scope, "", "").typeOf(n); t != "" {
+		return t
 	}
+	return "interface{}"
+}
 
-	// remove whitespace and format code
-	ret = strings.TrimSpace(ret)
-	formatted, err := format.Source([]byte(ret))
+func goExpr(expr, scope string) string {
+	return goExprAttr(expr, scope, "", "")
+}
+
+// goExprAttr translates an expression in the user type scope to Go code, _
+// is translated to currentAttr of type currentType. Invalid expressions are
+// returned unchanged, they are reported by checkExpr.
+func goExprAttr(expr, scope, currentAttr, currentType string) string {
+	n, err := parseExpr(expr)
+	if err != nil {
+		return expr
+	}
+	ret, _ := newExprContext(scope, currentAttr, currentType).code(n)
+	formatted, err := formatExpr(ret)
 	if err != nil {
-		log.Println(ret, err)
 		return ret
 	}
-	return string(formatted)
+	return formatted
+}
+
+// formatExpr formats a Go expression like gofmt.
+func formatExpr(code string) (string, error) {
+	fset := token.NewFileSet()
+	x, err := parser.ParseExprFrom(fset, "", code, 0)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, x); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// checkExpr returns the type of expr in the user type scope, _ is of type
+// currentType. It returns an error if expr is not a valid expression, has a
+// type error or is not translated to a valid Go expression.
+func checkExpr(expr, scope, currentType string) (string, error) {
+	n, err := parseExpr(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid expression %q: %v", expr, err)
+	}
+	c := newExprContext(scope, "value", currentType)
+	t := c.typeOf(n)
+	if c.err != nil {
+		return "", fmt.Errorf("invalid expression %q: %v", expr, c.err)
+	}
+	code, _ := c.code(n)
+	if _, err := formatExpr(code); err != nil {
+		return "", fmt.Errorf("invalid expression %q: %v", expr, err)
+	}
+	return t, nil
 }
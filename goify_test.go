@@ -14,14 +14,34 @@ type Result struct {
 
 func TestGoify(t *testing.T) {
 
-	kaitaiTypes = map[string]string{
-		"Itoa": "[]byte",
-		"len":  "int64",
-	}
+	typeScopes = map[string]*Type{}
 
 	tests := []Result{
-		// "_root._io":                                                       "k.RootBase().IO()",
-		// "_io.size - _root.sector_size":                                    "k.IO().Size() - k.RootBase.SectorSize()",
+		Result{
+			Input:  "_root._io",
+			GoCode: "k.Root().IO()",
+			Type:   "runtime.IO",
+		},
+		Result{
+			Input:  "_io.size - _root.sector_size",
+			GoCode: "k.IO().Size() - k.Root().SectorSize()",
+			Type:   "int64",
+		},
+		Result{
+			Input:  "_io.pos",
+			GoCode: "k.IO().Pos()",
+			Type:   "int64",
+		},
+		Result{
+			Input:  "not _io.eof",
+			GoCode: "!k.IO().EOF()",
+			Type:   "bool",
+		},
+		Result{
+			Input:  "a < 1 or a > 2",
+			GoCode: "k.A() < 1 || k.A() > 2",
+			Type:   "bool",
+		},
 		Result{
 			Input:  "true",
 			GoCode: "true",
@@ -40,46 +60,46 @@ func TestGoify(t *testing.T) {
 		Result{
 			Input:  "[0x20, 0x30, 0x40]",
 			GoCode: "[]byte{0x20, 0x30, 0x40}",
-			Type:   "int64", // TODO wrong
+			Type:   "[]byte",
 		},
 		Result{
 			Input:  "entries_start",
 			GoCode: "k.EntriesStart()",
-			Type:   "runtime.KSYDecoder",
+			Type:   "interface{}",
 		},
 		Result{
 			Input:  "entries_start.to_s",
-			GoCode: "strconv.Itoa(int(k.EntriesStart()))",
-			Type:   "[]byte",
+			GoCode: "strconv.FormatInt(int64(k.EntriesStart()), 10)",
+			Type:   "string",
 		},
 		Result{
 			Input:  "entries_start * _root.sector_size",
-			GoCode: "k.EntriesStart() * k.RootBase().SectorSize()",
+			GoCode: "k.EntriesStart() * k.Root().SectorSize()",
 			Type:   "int64",
 		},
 		Result{
 			Input:  "_root.block0.body.as<container_superblock>.block_size",
-			GoCode: "k.RootBase().Block0().Body().(ContainerSuperblock).BlockSize()",
-			Type:   "runtime.KSYDecoder",
+			GoCode: "k.Root().Block0().Body().(*ContainerSuperblock).BlockSize()",
+			Type:   "interface{}",
 		},
 		Result{
 			Input:  "(xp_desc_base + xp_desc_index) * _root.block_size",
-			GoCode: "(k.XpDescBase() + k.XpDescIndex()) * k.RootBase().BlockSize()",
+			GoCode: "(k.XpDescBase() + k.XpDescIndex()) * k.Root().BlockSize()",
 			Type:   "int64",
 		},
 		Result{
 			Input:  "(_parent.node_type & 4) == 0",
-			GoCode: "(k.ParentBase().NodeType() & 4) == 0",
+			GoCode: "(k.Parent().NodeType() & 4) == 0",
 			Type:   "bool",
 		},
 		Result{
 			Input:  "(_parent.level > 0) ? 256 : key_hdr.kind.to_i",
-			GoCode: "func()int64{if (k.ParentBase().Level() > 0){return 256}else{return int64(k.KeyHdr().Kind())}}()",
+			GoCode: "func() int64 {\n\tif k.Parent().Level() > 0 {\n\t\treturn 256\n\t}\n\treturn int64(k.KeyHdr().Kind())\n}()",
 			Type:   "int64",
 		},
 		Result{
 			Input:  "_root.block_size - data_offset - 40 * (_parent.node_type & 1)",
-			GoCode: "k.RootBase().BlockSize() - k.DataOffset() - 40*(k.ParentBase().NodeType()&1)",
+			GoCode: "k.Root().BlockSize() - k.DataOffset() - 40*(k.Parent().NodeType()&1)",
 			Type:   "int64",
 		},
 		Result{
@@ -89,13 +109,13 @@ func TestGoify(t *testing.T) {
 		},
 		Result{
 			Input:  "xf_header[_index].length + ((8 - xf_header[_index].length) % 8)",
-			GoCode: "len(k.XfHeader()[index]) + ((8 - len(k.XfHeader()[index])) % 8)",
+			GoCode: "int64(len(k.XfHeader()[index])) + runtime.Mod(8-int64(len(k.XfHeader()[index])), 8)",
 			Type:   "int64",
 		},
 		Result{
 			Input:  "\"test\"",
 			GoCode: "\"test\"",
-			Type:   "[]byte",
+			Type:   "string",
 		},
 		Result{
 			Input:  "-2.7",
@@ -114,7 +134,7 @@ func TestGoify(t *testing.T) {
 		},
 		Result{
 			Input:  "-9837 % 13",
-			GoCode: "-9837 % 13",
+			GoCode: "(-9837%13 + 13) % 13",
 			Type:   "int64",
 		},
 		Result{
@@ -125,24 +145,47 @@ func TestGoify(t *testing.T) {
 		Result{
 			Input:  "\"test\" + \"test\"",
 			GoCode: "\"test\" + \"test\"",
-			Type:   "[]byte",
+			Type:   "string",
 		},
 		Result{
 			Input:  "str1.length",
 			GoCode: "len(k.Str1())",
-			Type:   "int64",
+			Type:   "int",
 		},
 		Result{
 			Input:  "'foo'",
 			GoCode: "\"foo\"",
-			Type:   "[]byte",
+			Type:   "string",
 		},
 	}
 
 	for _, result := range tests {
 		assert.EqualValues(t, result.GoCode, goExpr(result.Input, ""))
-		ty := getType(result.Input)
+		ty := getType(result.Input, "")
 		// fmt.Println("cmp", result.Type, ty, goExpr(result.Input, ""))
 		assert.EqualValues(t, result.Type, ty)
 	}
 }
+
+func TestSplitArgs(t *testing.T) {
+	assert.EqualValues(t, []string{"5"}, splitArgs("5"))
+	assert.EqualValues(t, []string{"2 + 3", "true"}, splitArgs("2 + 3, true"))
+	assert.EqualValues(t, []string{"foo(1, 2)", "[1, 2]", "','"}, splitArgs("foo(1, 2), [1, 2], ','"))
+	assert.Empty(t, splitArgs(""))
+}
+
+func TestGoEnum(t *testing.T) {
+	enumTypes = map[string]string{}
+	setupEnums(&Type{
+		Enums: map[string]map[int]interface{}{"opcodes": {0x53: "a_string"}},
+		Types: map[string]Type{
+			"container1": {Enums: map[string]map[int]interface{}{"animal": {4: "dog"}}},
+		},
+	})
+
+	assert.EqualValues(t, "Opcodes", getEnumType("opcodes"))
+	assert.EqualValues(t, "Container1Animal", getEnumType("animal"))
+	assert.EqualValues(t, "Container1Animal", getEnumType("container1::animal"))
+	assert.EqualValues(t, "k.Opcode() == OpcodesAString", goExpr("opcode == opcodes::a_string", ""))
+	assert.EqualValues(t, "int64(Container1AnimalDog)", goExpr("container1::animal::dog.to_i", ""))
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/pkg/errors"
+)
+
+// searchPaths holds the directories given with -I, which are used to resolve
+// absolute imports and relative imports not found next to the importing file.
+type searchPaths []string
+
+func (p *searchPaths) String() string {
+	return strings.Join(*p, string(os.PathListSeparator))
+}
+
+func (p *searchPaths) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+var includes searchPaths
+
+// Spec is a parsed .ksy file together with its resolved imports.
+type Spec struct {
+	Path    string
+	Type    Type
+	Imports []*Spec
+}
+
+// Dir returns the directory of the spec, which is also its Go package.
+func (s *Spec) Dir() string {
+	return filepath.Dir(s.Path)
+}
+
+// Package returns the Go package name of the spec.
+func (s *Spec) Package() string {
+	return filepath.Base(s.Dir())
+}
+
+// TypeName returns the Go type name of the spec's root type.
+func (s *Spec) TypeName() string {
+	return strcase.ToCamel(s.Type.Meta.ID)
+}
+
+// Reaches returns true if target is imported by the spec, directly or
+// through other imports.
+func (s *Spec) Reaches(target *Spec, seen map[*Spec]bool) bool {
+	if seen[s] {
+		return false
+	}
+	seen[s] = true
+	for _, imported := range s.Imports {
+		if imported == target || imported.Reaches(target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// specs caches all parsed specs by absolute path, so every spec is parsed
+// only once, even with circular imports.
+var specs = map[string]*Spec{}
+
+func loadSpec(ksyPath string, debug bool) (*Spec, error) {
+	absPath, err := filepath.Abs(ksyPath)
+	if err != nil {
+		return nil, err
+	}
+	if spec, ok := specs[absPath]; ok {
+		return spec, nil
+	}
+
+	source, err := ioutil.ReadFile(absPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "read source")
+	}
+
+	// parse generic
+	m := make(map[interface{}]interface{})
+	err = YAMLUnmarshal("generic", source, &m, absPath, debug)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse generic yaml")
+	}
+
+	// parse kaitai
+	spec := &Spec{Path: absPath}
+	err = YAMLUnmarshal("kaitai", source, &spec.Type, absPath, debug)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse kaitai yaml")
+	}
+	specs[absPath] = spec
+
+	for _, name := range spec.Type.Meta.Imports {
+		importPath, err := resolveImport(name, spec.Dir())
+		if err != nil {
+			return nil, err
+		}
+		imported, err := loadSpec(importPath, debug)
+		if err != nil {
+			return nil, errors.Wrap(err, "import "+name)
+		}
+		spec.Imports = append(spec.Imports, imported)
+	}
+	return spec, nil
+}
+
+// resolveImport finds the .ksy file of an import. Relative imports are
+// looked up next to the importing file first. Every candidate is tried as
+// name.ksy and as name/base.ksy, the layout used for one package per spec.
+func resolveImport(name, dir string) (string, error) {
+	var dirs []string
+	if !strings.HasPrefix(name, "/") {
+		dirs = append(dirs, dir)
+	}
+	dirs = append(dirs, includes...)
+
+	for _, d := range dirs {
+		candidate := filepath.Join(d, filepath.FromSlash(name))
+		for _, file := range []string{candidate + ".ksy", filepath.Join(candidate, path.Base(name)+".ksy")} {
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				return file, nil
+			}
+		}
+	}
+	return "", errors.Errorf("import %s not found in %s", name, strings.Join(dirs, ", "))
+}
+
+// goImportPath returns the Go import path of dir based on the module path in
+// the next go.mod above it.
+func goImportPath(dir string) (string, error) {
+	for modDir := dir; ; modDir = filepath.Dir(modDir) {
+		f, err := os.Open(filepath.Join(modDir, "go.mod"))
+		if err == nil {
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if strings.HasPrefix(line, "module ") {
+					module := strings.Trim(strings.TrimSpace(line[len("module "):]), "\"")
+					rel, err := filepath.Rel(modDir, dir)
+					if err != nil {
+						return "", err
+					}
+					return path.Join(module, filepath.ToSlash(rel)), nil
+				}
+			}
+			return "", errors.Errorf("no module path in %s", filepath.Join(modDir, "go.mod"))
+		}
+		if filepath.Dir(modDir) == modDir {
+			return "", errors.Errorf("no go.mod found for %s", dir)
+		}
+	}
+}
+
+var importTypes map[string]string
+
+func addImportType(kaitaiName, goName string) {
+	if _, ok := importTypes[kaitaiName]; !ok {
+		importTypes[kaitaiName] = goName
+	}
+}
+
+func getImportType(kaitaiName string) (string, bool) {
+	goName, ok := importTypes[kaitaiName]
+	return goName, ok
+}
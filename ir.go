@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"strings"
+	"text/template"
+)
+
+// The generator translates the parsed Type tree into the intermediate
+// representation below, which is lowered into Go code by the templates in the
+// templates directory. All expressions are translated into Go, when the nodes
+// are built, so the templates only arrange the code. Nodes are kept in slices
+// to generate the same code for the same spec.
+
+// File is a generated Go file.
+type File struct {
+	Header  string
+	Package string
+	Imports []string
+	Types   []*TypeNode
+}
+
+// TypeNode is a user type. Nested types are flattened into the list of types
+// of the file.
+type TypeNode struct {
+	Name      string
+	Parent    string
+	Root      string
+	Doc       string
+	Params    []*ParamNode
+	Seq       []*FieldNode
+	Instances []*FieldNode
+	Endian    *EndianNode
+	Enums     []*EnumNode
+}
+
+// IsRoot returns true for the root type of a spec, which gets the public
+// Decode and Encode entry points.
+func (t *TypeNode) IsRoot() bool {
+	return t.Name == t.Root
+}
+
+// Deferrable returns true, if the type has fields, that are skipped in lazy
+// mode.
+func (t *TypeNode) Deferrable() bool {
+	for _, field := range t.Seq {
+		if field.DeferSize != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ParamNode is a parameter of a user type.
+type ParamNode struct {
+	ID     string
+	Name   string
+	Getter string
+	Type   string
+}
+
+// EndianNode sets the calculated endianness of a type while parsing. Without a
+// default case the endianness is undecided for unknown values.
+type EndianNode struct {
+	On      string
+	Cases   []*EndianCase
+	Default *EndianCase
+}
+
+// EndianCase is a condition on the switch-on value of a calculated
+// endianness.
+type EndianCase struct {
+	Cond      string
+	BigEndian bool
+}
+
+// FieldNode is a seq attribute or an instance of a type.
+type FieldNode struct {
+	ID       string
+	Category string
+	Recv     string
+	Name     string
+	Title    string
+	Getter   string
+	DataType string
+	Doc      string
+
+	// Lazy is set, if the read function passes the lazy flag to user types.
+	Lazy bool
+	// DeferSize is set for fields, that are skipped in lazy mode.
+	DeferSize string
+	// DeferEOS is set, if the skipped field extends to the end of the stream.
+	DeferEOS bool
+	// DeferCheck is the unsigned 64-bit size, that is checked before it is
+	// converted to int64.
+	DeferCheck string
+
+	Read  *ReadNode
+	Write *WriteNode
+}
+
+// ReadCall returns the call of the read function of the field.
+func (f *FieldNode) ReadCall(lazy string) string {
+	if !f.Lazy {
+		lazy = ""
+	}
+	return "k.read" + f.Title + "(" + lazy + ")"
+}
+
+// Tag returns the struct tag of the field.
+func (f *FieldNode) Tag() string {
+	return "`ks:\"" + f.ID + "," + f.Category + "\"`"
+}
+
+// ReadNode is the body of the read function of a field. It either calculates
+// a value, repeats an element or reads a single element. Stream is set to
+// read from the stream of another object.
+type ReadNode struct {
+	If      string
+	Stream  string
+	Seek    *SeekNode
+	Value   string
+	Repeat  *RepeatNode
+	Switch  *SwitchNode
+	New     string
+	Elem    *ElemNode
+	Process *ProcessNode
+	Valid   *ValidNode
+}
+
+// Reader returns the stream the field is read from, parse errors report its
+// position.
+func (r *ReadNode) Reader() string {
+	if r.Value != "" {
+		// values are not read from a stream
+		return "nil"
+	}
+	if r.Stream != "" {
+		return "stream"
+	}
+	return "k.Stream"
+}
+
+// SeekNode moves Stream to Pos relative to Whence. The position is restored,
+// when the read function returns.
+type SeekNode struct {
+	Stream string
+	Pos    string
+	Whence string
+}
+
+// RepeatNode reads elements until Cond is false, Until is true or, with EOS,
+// the stream ends. Slices are appended to, arrays are indexed.
+type RepeatNode struct {
+	ElemType string
+	Cond     string
+	EOS      bool
+	Until    string
+	New      string
+	Append   bool
+	Elem     *ElemNode
+	Valid    *ValidNode
+}
+
+// ValidNode checks the contents and valid constraints of a value read from
+// Stream.
+type ValidNode struct {
+	Stream string
+	Checks []*ValidCheck
+}
+
+// ValidCheck returns Err, if Failed is true.
+type ValidCheck struct {
+	Failed string
+	Err    string
+}
+
+// SwitchNode chooses the user type of a field. The default case has no
+// value.
+type SwitchNode struct {
+	On    string
+	Cases []*SwitchCase
+}
+
+// SwitchCase is a case of a SwitchNode.
+type SwitchCase struct {
+	Value string
+	New   string
+}
+
+// ElemNode reads a single value from Stream into Holder. Exactly one of
+// Bytes, Native and User is set.
+type ElemNode struct {
+	Holder string
+	Stream string
+	// SizeCheck is the unsigned 64-bit size, that is checked before it is
+	// converted to int64.
+	SizeCheck string
+	Bytes     *BytesNode
+	Native    *NativeNode
+	User      *UserNode
+}
+
+// Reader returns the stream user types are read from.
+func (e *ElemNode) Reader() string {
+	if e.Stream == "k" {
+		return "k.Stream"
+	}
+	return e.Stream
+}
+
+// BytesNode reads a byte array or string. The raw bytes are stripped of Pad,
+// cut at Term and decoded with Encoding, if set.
+type BytesNode struct {
+	Read     string
+	Pad      string
+	Term     string
+	Include  string
+	Encoding string
+}
+
+// Direct returns true, if the raw bytes are the value.
+func (b *BytesNode) Direct() bool {
+	return b.Pad == "" && b.Term == "" && b.Encoding == ""
+}
+
+// ByteOrder holds the code variants of a field depending on the endianness.
+// Code is used for a fixed endianness and little endian, BE is only set for a
+// calculated endianness with a different big endian variant.
+type ByteOrder struct {
+	Code string
+	BE   string
+}
+
+// NativeNode reads an integer, float or enum. Enums are read as Raw and
+// converted to Enum.
+type NativeNode struct {
+	ByteOrder
+	Raw  string
+	Enum string
+}
+
+// UserNode reads a user type. Sized user types are read from the substream
+// returned by the Substream call, so the parent continues right after the
+// sized data, whatever the type consumes.
+type UserNode struct {
+	Ancestors string
+	Substream string
+	Err       string
+}
+
+// ProcessNode applies a process routine: Assign = Call.
+type ProcessNode struct {
+	Assign string
+	Call   string
+}
+
+// WriteNode is the body of the write function of a seq attribute.
+type WriteNode struct {
+	If     string
+	Repeat string
+	Elem   *WriteElemNode
+}
+
+// WriteElemNode writes a single Value. Contents are written as they are,
+// otherwise exactly one of Bytes, Native and User is set.
+type WriteElemNode struct {
+	Value    string
+	Contents string
+	Bytes    *WriteBytesNode
+	Native   *ByteOrder
+	User     *WriteUserNode
+}
+
+// WriteBytesNode writes a byte array or string. Strings are encoded with
+// Encoding, Process is the inverse process routine.
+type WriteBytesNode struct {
+	Encoding string
+	Process  *ProcessNode
+	Write    string
+}
+
+// WriteUserNode writes a user type through Encoder. NilCheck fails for unset
+// fields, sized types are padded to Size.
+type WriteUserNode struct {
+	ID       string
+	Encoder  string
+	NilCheck bool
+	Size     string
+}
+
+// EnumNode is a named integer type with a const for every value.
+type EnumNode struct {
+	Type      string
+	Values    []*EnumValue
+	HasOrigID bool
+}
+
+// Consts returns the names of all consts of the enum.
+func (e *EnumNode) Consts() string {
+	consts := make([]string, len(e.Values))
+	for i, value := range e.Values {
+		consts[i] = value.Const
+	}
+	return strings.Join(consts, ", ")
+}
+
+// EnumValue is a value of an enum.
+type EnumValue struct {
+	Const  string
+	Value  int
+	Name   string
+	Doc    []string
+	OrigID string
+}
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+var templates = template.Must(template.ParseFS(templateFiles, "templates/*.tmpl"))
+
+// Render lowers the file into Go code. The code is not formatted yet.
+func (f *File) Render() ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := templates.ExecuteTemplate(&buffer, "file", f); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const renderSpec = `
+meta:
+  id: render
+  endian: le
+seq:
+  - id: len_data
+    type: u1
+  - id: data
+    size: len_data
+  - id: len_big
+    type: u8
+  - id: big
+    size: len_big
+  - id: kind
+    type: u2
+    enum: kind
+  - id: body
+    type:
+      switch-on: kind
+      cases:
+        'kind::b': body_b
+        'kind::a': body_a
+instances:
+  twice:
+    value: len_data * 2
+  header:
+    pos: 0
+    type: body_a
+types:
+  body_b:
+    seq:
+      - id: b
+        type: strz
+        encoding: ASCII
+  body_a:
+    seq:
+      - id: a
+        type: u4
+enums:
+  kind:
+    2: b
+    1: a
+`
+
+func renderTestFile(t *testing.T) []byte {
+	var kaitai Type
+	if err := yaml.Unmarshal([]byte(renderSpec), &kaitai); err != nil {
+		t.Fatal(err)
+	}
+	enumTypes = map[string]string{}
+	parents = map[string]string{}
+	typeParams = map[string][]Attribute{}
+	importTypes = map[string]string{}
+	typeScopes = map[string]*Type{}
+	setupEnums(&kaitai)
+	setupMap(&kaitai, "Render")
+
+	file := &File{
+		Package: "render",
+		Imports: []string{"github.com/go-ee/kaitaigo/runtime"},
+		Types:   kaitai.Node("Render", "Render", "Render"),
+	}
+	code, err := file.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestRender(t *testing.T) {
+	code := renderTestFile(t)
+	if _, err := parser.ParseFile(token.NewFileSet(), "render.go", code, 0); err != nil {
+		t.Fatalf("%s\n%s", err, code)
+	}
+	assert.Equal(t, string(code), string(renderTestFile(t)), "same spec, same code")
+
+	assert.Contains(t, string(code), "func (k *Render) readTwice() (ret int64, err error) {")
+	assert.Contains(t, string(code), "func (k *Render) readHeader(lazy bool) (ret *BodyA, err error) {")
+	assert.Contains(t, string(code), "ret, err = k.ReadBytes(int64(k.LenData()))")
+	assert.NotContains(t, string(code), "runtime.CheckSize(k.LenData())")
+	assert.Contains(t, string(code), "if err = runtime.CheckSize(k.LenBig()); err == nil {")
+	assert.Contains(t, string(code), "if err := runtime.CheckSize(k.LenBig()); err != nil {")
+	assert.Contains(t, string(code), "if _, err = k.Seek(int64(0), io.SeekStart); err != nil {")
+	assert.Contains(t, string(code), "case KindA:\n\t\tret = &BodyA{}\n\tcase KindB:\n\t\tret = &BodyB{}\n")
+	assert.Contains(t, string(code), "KindA Kind = 1\n\tKindB Kind = 2\n")
+}
+
+func TestSpecOrder(t *testing.T) {
+	code := string(renderTestFile(t))
+	assert.Less(t, strings.Index(code, "func (k *Render) readTwice("), strings.Index(code, "func (k *Render) readHeader("), "instances")
+	assert.Less(t, strings.Index(code, "type BodyB struct"), strings.Index(code, "type BodyA struct"), "types")
+}
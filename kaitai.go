@@ -2,31 +2,81 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/iancoleman/strcase"
+	"gopkg.in/yaml.v3"
 )
 
 type Meta struct {
-	ID            string `yaml:"id,omitempty"`
-	Title         string `yaml:"title,omitempty"`
-	Application   string `yaml:"application,omitempty"`
-	Imports       string `yaml:"imports,omitempty"`
-	Encoding      string `yaml:"encoding,omitempty"`
-	Endian        string `yaml:"endian,omitempty"`
-	KSVersion     string `yaml:"ks-version,omitempty"`
-	KSDebug       string `yaml:"ks-debug,omitempty"`
-	KSOpaqueTypes string `yaml:"ksopaquetypes,omitempty"`
-	Licence       string `yaml:"licence,omitempty"`
-	FileExtension string `yaml:"fileextension,omitempty"`
+	ID            string   `yaml:"id,omitempty"`
+	Title         string   `yaml:"title,omitempty"`
+	Application   string   `yaml:"application,omitempty"`
+	Imports       []string `yaml:"imports,omitempty"`
+	Encoding      string   `yaml:"encoding,omitempty"`
+	Endian        Endian   `yaml:"endian,omitempty"`
+	BitEndian     string   `yaml:"bit-endian,omitempty"`
+	KSVersion     string   `yaml:"ks-version,omitempty"`
+	KSDebug       string   `yaml:"ks-debug,omitempty"`
+	KSOpaqueTypes string   `yaml:"ksopaquetypes,omitempty"`
+	Licence       string   `yaml:"licence,omitempty"`
+	FileExtension string   `yaml:"fileextension,omitempty"`
 }
 
-var endian = "binary.LittleEndian"
+// Endian is the default byte order of a type, either fixed to "le" or "be" or
+// calculated while parsing with switch-on.
+type Endian struct {
+	Value    string
+	SwitchOn string            `yaml:"switch-on,omitempty"`
+	Cases    map[string]string `yaml:"cases,omitempty"`
 
-var endianess = map[string]string{
-	"le": "binary.LittleEndian",
-	"be": "binary.BigEndian",
+	// inherited calculated endianness of an enclosing type
+	inherited bool
+}
+
+func (y *Endian) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&y.Value)
+	}
+	var calculated struct {
+		SwitchOn string            `yaml:"switch-on"`
+		Cases    map[string]string `yaml:"cases"`
+	}
+	if err := value.Decode(&calculated); err != nil {
+		return err
+	}
+	y.SwitchOn, y.Cases = calculated.SwitchOn, calculated.Cases
+	return nil
+}
+
+// IsZero returns true if no endianness is given.
+func (y *Endian) IsZero() bool {
+	return y.Value == "" && y.SwitchOn == "" && !y.inherited
+}
+
+// Calculated returns true if the endianness is only known while parsing.
+func (y *Endian) Calculated() bool {
+	return y.SwitchOn != "" || y.inherited
+}
+
+// Inherit returns the endianness of a type nested in a type with endianness y.
+// Calculated endianness is passed down at runtime.
+func (y Endian) Inherit() Endian {
+	if y.Calculated() {
+		return Endian{inherited: true}
+	}
+	return Endian{Value: y.Value}
+}
+
+// Default returns the fixed endianness, which is little endian if none is
+// given.
+func (y *Endian) Default() string {
+	if y.Value == "" {
+		return "le"
+	}
+	return y.Value
 }
 
 type TypeSwitch struct {
@@ -34,29 +84,97 @@ type TypeSwitch struct {
 	Cases    map[string]TypeKey `yaml:"cases,omitempty"`
 }
 
+// CaseValues returns the sorted values of the cases, the default case "_"
+// comes last.
+func (y *TypeSwitch) CaseValues() []string {
+	values := make([]string, 0, len(y.Cases))
+	for value := range y.Cases {
+		if value != "_" {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	if _, ok := y.Cases["_"]; ok {
+		values = append(values, "_")
+	}
+	return values
+}
+
 type TypeKey struct {
 	Type       string
+	Args       []string
 	TypeSwitch TypeSwitch
 	CustomType bool
 }
 
-func (y *TypeKey) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	err := unmarshal(&y.Type)
-	if err != nil {
-		err = unmarshal(&y.TypeSwitch)
+func (y *TypeKey) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return value.Decode(&y.TypeSwitch)
+	}
+	if err := value.Decode(&y.Type); err != nil {
 		return err
 	}
+	// parameterised type invocation, e.g. my_type(len, true)
+	if i := strings.Index(y.Type, "("); i != -1 && strings.HasSuffix(y.Type, ")") {
+		y.Args = splitArgs(y.Type[i+1 : len(y.Type)-1])
+		y.Type = strings.TrimSpace(y.Type[:i])
+	}
 	if _, ok := typeMapping[y.Type]; !ok {
 		y.CustomType = true
 	}
 	return nil
 }
 
+// Imported returns true if the type is the root type of an imported spec.
+func (y *TypeKey) Imported() bool {
+	_, ok := getImportType(y.Type)
+	return ok
+}
+
+// Literal returns a composite literal of the user type with all parameters
+// set to the arguments translated in scope. Types of other packages with
+// parameters are created with their constructor, because their fields are
+// unexported.
+func (y *TypeKey) Literal(scope string) string {
+	typeName := y.String()
+	params := getTypeParams(typeName)
+	values := []string{}
+	fields := []string{}
+	for i, arg := range y.Args {
+		if i >= len(params) {
+			break
+		}
+		value := goExpr(arg, scope)
+		paramType := params[i].ParamType()
+		if isNative(paramType) && paramType != "[]byte" && paramType != "string" {
+			value = paramType + "(" + value + ")"
+		}
+		values = append(values, value)
+		fields = append(fields, params[i].Name()+": "+value)
+	}
+	if i := strings.Index(typeName, "."); i != -1 && len(values) > 0 {
+		return "*" + typeName[:i+1] + "New" + typeName[i+1:] + "(" + strings.Join(values, ", ") + ")"
+	}
+	return typeName + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// New returns a pointer to a new instance of the user type.
+func (y *TypeKey) New(scope string) string {
+	literal := y.Literal(scope)
+	if strings.HasPrefix(literal, "*") {
+		return literal[1:]
+	}
+	return "&" + literal
+}
+
 func (y *TypeKey) String() string {
 	if y.Type != "" {
 		if val, ok := typeMapping[y.Type]; ok {
 			return val
 		}
+		if val, ok := getImportType(y.Type); ok {
+			return val
+		}
 		return strcase.ToCamel(y.Type)
 	} else if y.TypeSwitch.SwitchOn != "" {
 		return "runtime.Decoder"
@@ -70,36 +188,96 @@ type Contents struct {
 	TypeSwitch    TypeSwitch
 }
 
-func (y *Contents) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	err := unmarshal(&y.ContentString)
-	if err != nil {
-		err := unmarshal(&y.ContentArray)
-		if err != nil {
-			err = unmarshal(&y.TypeSwitch)
-			return err
+func (y *Contents) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&y.ContentString)
+	case yaml.SequenceNode:
+		return value.Decode(&y.ContentArray)
+	}
+	return value.Decode(&y.TypeSwitch)
+}
+
+// Raw returns the contents as bytes. Arrays may mix strings and integers.
+func (y *Contents) Raw() []byte {
+	if len(y.ContentString) != 0 {
+		return []byte(y.ContentString)
+	}
+	raw := []byte{}
+	for _, value := range y.ContentArray {
+		switch v := value.(type) {
+		case string:
+			raw = append(raw, v...)
+		case int:
+			raw = append(raw, byte(v))
+		case uint64:
+			raw = append(raw, byte(v))
+		}
+	}
+	return raw
+}
+
+// Check returns an error for values, that are neither strings nor bytes.
+func (y *Contents) Check() error {
+	for _, value := range y.ContentArray {
+		switch v := value.(type) {
+		case string:
+		case int:
+			if v < 0 || v > 255 {
+				return fmt.Errorf("contents value %d is not a byte", v)
+			}
+		case uint64:
+			if v > 255 {
+				return fmt.Errorf("contents value %d is not a byte", v)
+			}
+		default:
+			return fmt.Errorf("invalid contents value %v", v)
 		}
-		return err
 	}
 	return nil
 }
 
 func (y *Contents) Len() int {
+	return len(y.Raw())
+}
+
+// Bytes returns the contents as Go byte slice literal.
+func (y *Contents) Bytes() string {
 	if len(y.ContentString) != 0 {
-		return len(y.ContentString)
+		return "[]byte(" + strconv.Quote(y.ContentString) + ")"
 	}
-	if len(y.ContentArray) == 0 {
-		return 0
+	values := []string{}
+	for _, b := range y.Raw() {
+		values = append(values, fmt.Sprintf("0x%02x", b))
 	}
-	switch v := y.ContentArray[0].(type) {
-	case string:
-		return len(v)
-	default:
-		return len(y.ContentArray)
+	return "[]byte{" + strings.Join(values, ", ") + "}"
+}
+
+// Valid are the constraints a parsed value must fulfill. A plain value is the
+// short form of eq.
+type Valid struct {
+	Eq    string   `yaml:"eq,omitempty"`
+	Min   string   `yaml:"min,omitempty"`
+	Max   string   `yaml:"max,omitempty"`
+	AnyOf []string `yaml:"any-of,omitempty"`
+	Expr  string   `yaml:"expr,omitempty"`
+}
+
+func (y *Valid) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&y.Eq)
 	}
+	type plain Valid
+	return value.Decode((*plain)(y))
+}
+
+// IsZero returns true if no constraint is set.
+func (y Valid) IsZero() bool {
+	return y.Eq == "" && y.Min == "" && y.Max == "" && len(y.AnyOf) == 0 && y.Expr == ""
 }
 
 type Attribute struct {
-	Category    string   `-`
+	Category    string   `yaml:"-"`
 	ID          string   `yaml:"id,omitempty"`
 	Type        TypeKey  `yaml:"type"`
 	Size        string   `yaml:"size,omitempty"`
@@ -112,6 +290,7 @@ type Attribute struct {
 	Value       string   `yaml:"value,omitempty"`
 	Pos         string   `yaml:"pos,omitempty"`
 	Whence      string   `yaml:"whence,omitempty"`
+	IO          string   `yaml:"io,omitempty"`
 	Enum        string   `yaml:"enum,omitempty"`
 	If          string   `yaml:"if,omitempty"`
 	Process     string   `yaml:"process,omitempty"`
@@ -120,34 +299,64 @@ type Attribute struct {
 	Include     string   `yaml:"include,omitempty"`
 	EosError    string   `yaml:"eos-error,omitempty"`
 	Pad         string   `yaml:"pad-right,omitempty"`
-	// Encoding    string   `yaml:"encoding,omitempty"`
+	Encoding    string   `yaml:"encoding,omitempty"`
+	Valid       Valid    `yaml:"valid,omitempty"`
+
+	// path of the attribute in the spec, e.g. "/types/header/seq/0"
+	path string
+	// node of the attribute in the spec, for the position of diagnostics
+	node *yaml.Node
+	// Go name of the type declaring the attribute, the scope of its
+	// expressions
+	scope string
+}
+
+func (k *Attribute) UnmarshalYAML(value *yaml.Node) error {
+	type plain Attribute
+	if err := value.Decode((*plain)(k)); err != nil {
+		return err
+	}
+	k.node = value
+	return nil
 }
 
 func (k *Attribute) Name() string {
-	return strcase.ToLowerCamel(k.ID)
+	// ToLowerCamel keeps the upper case of _unnamed2, the field would clash
+	// with its getter
+	return strcase.ToLowerCamel(strings.TrimPrefix(k.ID, "_"))
 }
 
 func (k *Attribute) ChildType() string {
+	if k.Enum != "" {
+		return getEnumType(k.Enum)
+	}
 	dataType := k.Type.String()
 	if dataType == "[]byte" { // || dataType == "runtime.String" {
 		if k.Value != "" {
-			dataType = getType(k.Value)
-		} else if k.Size != "" {
-			k.Size = strings.Replace(k.Size, "%", "%%", -1)
-		} else if k.Contents.Len() != 0 {
+			dataType = getType(k.Value, k.scope)
+		} else if k.Size == "" && k.Contents.Len() != 0 {
 			k.Size = fmt.Sprintf("%d", k.Contents.Len())
 		}
 	}
 	return dataType
 }
 
+// RawType returns the Go type an enum is read as. Bit sized enums are read as
+// integers, even single bits.
+func (k *Attribute) RawType() string {
+	if _, _, ok := bitType(k.Type.Type, ""); ok {
+		return "uint64"
+	}
+	return k.Type.String()
+}
+
 func (k *Attribute) DataType() string {
 	dataType := k.ChildType()
 	if k.Repeat != "" {
 		if !isInt(k.RepeatExpr) || k.Repeat == "eos" {
 			dataType = "[]" + dataType
 		} else {
-			dataType = "[" + goExpr(k.RepeatExpr, "") + "]" + dataType
+			dataType = "[" + goExpr(k.RepeatExpr, k.scope) + "]" + dataType
 		}
 	} else if k.Type.CustomType {
 		dataType = "*" + dataType
@@ -155,503 +364,720 @@ func (k *Attribute) DataType() string {
 	return dataType
 }
 
-func (k *Attribute) String() string {
-	doc := ""
-	if k.Doc != "" {
-		doc = "\n/* " + strings.TrimSpace(k.Doc) + "*/"
+var paramTypeMapping = map[string]string{
+	"bool":   "bool",
+	"bytes":  "[]byte",
+	"struct": "interface{}",
+	"any":    "interface{}",
+	"io":     "runtime.IO",
+}
+
+// ParamType returns the Go type of a type parameter.
+func (k *Attribute) ParamType() string {
+	t := strings.TrimSuffix(k.Type.Type, "[]")
+	dataType, ok := paramTypeMapping[t]
+	if !ok {
+		dataType = k.Type.String()
+		if k.Type.CustomType {
+			dataType = "*" + dataType
+		}
 	}
+	if t != k.Type.Type {
+		dataType = "[]" + dataType
+	}
+	return dataType
+}
 
-	return k.Name() + " " + k.DataType() + "`ks:\"" + k.ID + "," + k.Category + "\"`" + doc
+// Deferrable returns true if the attribute can be skipped in lazy mode, which
+// requires a byte array or user type with a known size. Attributes with
+// contents or valid are never skipped, their errors must be reported by
+// DecodeLazy.
+func (k *Attribute) Deferrable() bool {
+	if k.Value != "" || k.Repeat != "" || k.If != "" || k.Pos != "" || k.Terminator != "" {
+		return false
+	}
+	if k.Contents.Len() != 0 || !k.Valid.IsZero() {
+		return false
+	}
+	if k.Size == "" && k.SizeEos == "" {
+		return false
+	}
+	return k.ChildType() == "[]byte" || k.Type.CustomType
 }
 
 type Type struct {
 	Meta      Meta                           `yaml:"meta,omitempty"`
+	Params    []Attribute                    `yaml:"params,omitempty"`
 	Types     map[string]Type                `yaml:"types,omitempty"`
 	Seq       []Attribute                    `yaml:"seq,omitempty"`
 	Enums     map[string]map[int]interface{} `yaml:"enums,omitempty"`
 	Doc       string                         `yaml:"doc,omitempty"`
 	Instances map[string]Attribute           `yaml:"instances,omitempty"`
-}
 
-func (k *Type) InitElem(attrHolder string, errHolder string, attr Attribute, dataType string, init bool) (goCode string) {
-	var buffer LineBuffer
+	// path of the type below the root type, e.g. "container1::container2"
+	path string
 
-	// defer func() { buffer.WriteLine("k.Stream.SetErr(err)"); goCode = buffer.String() }()
-	defer func() { goCode = buffer.String() }()
+	// node of the type in the spec, for the position of diagnostics
+	node *yaml.Node
+	// Go names of the type and of its root type
+	name, root string
 
-	// init and parse element
-	if init && dataType != "[]byte" {
+	// names of the types, enums and instances in spec order
+	typeNames     []string
+	enumNames     []string
+	instanceNames []string
+}
 
+func (k *Type) UnmarshalYAML(value *yaml.Node) error {
+	type plain Type
+	if err := value.Decode((*plain)(k)); err != nil {
+		return err
 	}
-	if dataType == "[]byte" && attr.Size != "" {
-		//buffer.WriteLine(attrHolder + " = make([]byte, " + goExpr(attr.Size, "") + ")")
+	k.node = value
+	// seq attributes without id get a numbered id like in the reference
+	// compiler, e.g. _unnamed2
+	for i := range k.Seq {
+		if k.Seq[i].ID == "" {
+			k.Seq[i].ID = fmt.Sprintf("_unnamed%d", i)
+		}
 	}
+	// maps lose the spec order
+	k.typeNames = mappingKeys(valueNode(value, "types"))
+	k.enumNames = mappingKeys(valueNode(value, "enums"))
+	k.instanceNames = mappingKeys(valueNode(value, "instances"))
+	return nil
+}
 
-	terminated := attr.Terminator != "" || attr.Type.Type == "strz"
-	term := "byte(0)"
-	if attr.Terminator != "" {
-		term = goExpr(attr.Terminator, "")
+// valueNode returns the value of key in the mapping node or nil.
+func valueNode(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
 	}
-	resetPos := (attr.Size == "" && terminated) || attr.Size != ""
-
-	if resetPos {
-		// save position
-		//buffer.WriteLine("pos, _ := k.Seek(0, io.SeekCurrent)")
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
 	}
+	return nil
+}
 
-	// read data
-	if isNative(dataType) {
-		if strings.HasSuffix(attr.ID, "be") {
-			endian = "binary.BigEndian"
-		}
-		if attr.SizeEos != "" {
-			buffer.WriteLine(attrHolder + ", " + errHolder + " = k.ReadBytesFull()")
-		} else if terminated {
+// mappingKeys returns the keys of the mapping node in spec order.
+func mappingKeys(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	keys := make([]string, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
 
-			if attr.Size == "" {
-				buffer.WriteLine(attrHolder + ", " + errHolder + " =k.ReadBytes(" + term + ")")
-				buffer.WriteLine("if " + errHolder + " != nil && " + errHolder + " == io.EOF { " + errHolder + " = nil }")
-			} else {
-				// term & size
-				buffer.WriteLine("_, " + errHolder + " = k.Stream.Read(" + attrHolder + ")")
-			}
+// sortedKeys returns the ordered keys, if they are complete, or the sorted
+// keys of a type, that was not parsed from a spec.
+func sortedKeys(ordered []string, keys func() []string) []string {
+	if len(ordered) == len(keys()) {
+		return ordered
+	}
+	names := keys()
+	sort.Strings(names)
+	return names
+}
 
-			// eos
-			if attr.EosError == "" {
-				attr.EosError = "true"
-			}
-			buffer.WriteLine("if " + errHolder + " != nil && (" + errHolder + " != io.EOF || " + goExpr(attr.EosError, "") + ") {")
-			buffer.WriteLine("return")
-			buffer.WriteLine("}")
-			buffer.WriteLine(errHolder + " = nil")
+// TypeNames returns the names of the nested types in spec order.
+func (k *Type) TypeNames() []string {
+	return sortedKeys(k.typeNames, func() (names []string) {
+		for name := range k.Types {
+			names = append(names, name)
+		}
+		return
+	})
+}
 
-		} else {
-			buffer.WriteLine(fmt.Sprintf(attrHolder+", "+errHolder+" = k.%v", toReadFunc(&attr, "le")))
-		}
-	} else {
-		if resetPos {
-			buffer.WriteLine("var reader io.ReadSeeker")
-			buffer.WriteLine("if reader, err = k.ReadBytesAsReader(k.Length()); err != nil {")
-			buffer.WriteLine("return")
-			buffer.WriteLine("}")
-			buffer.WriteLine(attrHolder + ".Read(reader, lazy, k, k.Root())")
-		} else {
-			buffer.WriteLine(attrHolder + ".Read(k.Stream, lazy, k, k.Root())")
+// EnumNames returns the names of the enums in spec order.
+func (k *Type) EnumNames() []string {
+	return sortedKeys(k.enumNames, func() (names []string) {
+		for name := range k.Enums {
+			names = append(names, name)
 		}
+		return
+	})
+}
 
-		if attrHolder != "ret" {
-			buffer.WriteLine("if " + attrHolder + ".DecodeErr != nil {")
-			buffer.WriteLine(errHolder + " = " + attrHolder + ".DecodeErr")
-			buffer.WriteLine("}")
-		} else {
-			buffer.WriteLine("if k.DecodeErr != nil {")
-			buffer.WriteLine(errHolder + " = k.DecodeErr")
-			buffer.WriteLine("}")
+// InstanceNames returns the names of the instances in spec order.
+func (k *Type) InstanceNames() []string {
+	return sortedKeys(k.instanceNames, func() (names []string) {
+		for name := range k.Instances {
+			names = append(names, name)
 		}
-	}
+		return
+	})
+}
 
-	// pad
-	if attr.Pad != "" {
-		buffer.WriteLine(attrHolder + " = bytes.TrimRight(" + attrHolder + ", string(" + goExpr(attr.Pad, "") + "))")
+// SpecPath returns the path of an element of k in the spec, e.g.
+// SpecPath("seq", "0") is "/types/header/seq/0" for the type header.
+func (k *Type) SpecPath(key, name string) string {
+	path := ""
+	if k.path != "" {
+		path = "/types/" + strings.Replace(k.path, "::", "/types/", -1)
 	}
+	return path + "/" + key + "/" + name
+}
 
-	// term
-	if terminated && attr.Size != "" {
-		buffer.WriteLine("i := bytes.IndexByte(" + attrHolder + ", " + term + ")")
-		buffer.WriteLine("if i != -1 {")
-		buffer.WriteLine(attrHolder + " = " + attrHolder + "[:i+1]")
-		buffer.WriteLine("}")
+// SubPath returns the path of a type or enum declared in k.
+func (k *Type) SubPath(name string) string {
+	if k.path == "" {
+		return name
 	}
+	return k.path + "::" + name
+}
 
-	// calc new pos
-	if resetPos {
-		if attr.Size != "" {
-			//buffer.WriteLine("pos = pos+int64(" + goExpr(attr.Size, "") + ")")
-		} else if dataType == "[]byte" {
-			//buffer.WriteLine("pos = pos+int64(len(elem))")
-		}
+// Node builds the nodes of the type and its subtypes.
+func (k *Type) Node(typeName string, parent string, root string) []*TypeNode {
+	node := &TypeNode{
+		Name:   typeName,
+		Parent: parent,
+		Root:   root,
+		Doc:    strings.TrimSpace(k.Doc),
 	}
 
-	// include
-	// buffer.WriteLine("pos, _ := k.Seek(0, io.SeekCurrent)")
-	// buffer.WriteLine("fmt.Println(pos, elem)")
-	if (terminated) && attr.Include == "" { // && attr.Size == "" {
-		buffer.WriteLine(attrHolder + " = " + attrHolder + "[:len(" + attrHolder + ")-1]")
+	for _, param := range k.Params {
+		node.Params = append(node.Params, &ParamNode{
+			ID:     param.ID,
+			Name:   param.Name(),
+			Getter: strcase.ToCamel(param.Name()),
+			Type:   param.ParamType(),
+		})
 	}
 
-	// reset position
-	if resetPos {
-		if terminated && attr.Consume != "" {
-			// consume
-			buffer.WriteLine("if !" + goExpr(attr.Consume, "") + " {")
-			//buffer.WriteLine("pos -= 1")
-			buffer.WriteLine("}")
+	for i, attr := range k.Seq {
+		attr.Category = "attribute"
+		attr.path = k.SpecPath("seq", strconv.Itoa(i))
+		field := k.FieldNode(attr, typeName)
+		if attr.Deferrable() {
+			// lazy: remember the offset and skip the field
+			field.DeferSize = "0"
+			if attr.Size != "" {
+				field.DeferSize = "int64(" + goExpr(attr.Size, attr.scope) + ")"
+				field.DeferCheck = sizeCheck(attr)
+			} else {
+				field.DeferEOS = true
+			}
 		}
-		//buffer.WriteLine("_, err = k.Seek(pos, io.SeekStart)")
+		field.Write = k.WriteNode(attr)
+		node.Seq = append(node.Seq, field)
 	}
 
-	return
-}
-
-func (k *Type) CallAttr(attr Attribute) (ret string) {
-	if isNative(attr.DataType()) {
-		ret = "k.read" + strings.Title(attr.Name()) + "()"
-	} else {
-		ret = "k.read" + strings.Title(attr.Name()) + "(lazy)"
+	for _, name := range k.InstanceNames() {
+		inst := k.Instances[name]
+		inst.Category = "instance"
+		inst.ID = name
+		inst.path = k.SpecPath("instances", name)
+		node.Instances = append(node.Instances, k.FieldNode(inst, typeName))
 	}
-	return
-}
 
-func (k *Type) InitAttr(attr Attribute, typeName string) (goCode string) {
-	var buffer LineBuffer
+	if k.Meta.Endian.SwitchOn != "" {
+		node.Endian = k.EndianNode()
+	}
 
-	defer func() { goCode = buffer.String() }()
+	for _, enum := range k.EnumNames() {
+		node.Enums = append(node.Enums, k.EnumNode(enum))
+	}
 
-	if isNative(attr.DataType()) {
-		buffer.WriteLine("func (k *" + typeName + ") read" + strings.Title(attr.Name()) + "() (ret " + attr.DataType() + ", err error){")
-	} else {
-		buffer.WriteLine("func (k *" + typeName + ") read" + strings.Title(attr.Name()) + "(lazy bool) (ret " + attr.DataType() + ", err error){")
+	// subtypes are flattened
+	nodes := []*TypeNode{node}
+	for _, name := range k.TypeNames() {
+		t := k.Types[name]
+		t.path = k.SubPath(name)
+		if t.Meta.Endian.IsZero() {
+			t.Meta.Endian = k.Meta.Endian.Inherit()
+		}
+		if t.Meta.Encoding == "" {
+			t.Meta.Encoding = k.Meta.Encoding
+		}
+		if t.Meta.BitEndian == "" {
+			t.Meta.BitEndian = k.Meta.BitEndian
+		}
+		nodes = append(nodes, t.Node(strcase.ToCamel(name), getParent(strcase.ToCamel(name)), root)...)
 	}
+	return nodes
+}
 
-	var attrHolder, errHolder string
-	if attr.Value == "" {
-		attrHolder = "ret"
-		errHolder = "err"
-		//buffer.WriteLine("var elem " + attr.ChildType())
+// FieldNode builds the node of a seq attribute or instance with its read
+// function.
+func (k *Type) FieldNode(attr Attribute, typeName string) *FieldNode {
+	// the data type completes the size of contents
+	dataType := attr.DataType()
+	return &FieldNode{
+		ID:       attr.ID,
+		Category: attr.Category,
+		Recv:     typeName,
+		Name:     attr.Name(),
+		Title:    strings.Title(attr.Name()),
+		Getter:   strcase.ToCamel(attr.Name()),
+		DataType: dataType,
+		Doc:      strings.TrimSpace(attr.Doc),
+		Lazy:     !isNative(dataType),
+		Read:     k.ReadNode(attr),
 	}
-	// buffer.WriteLine("elem = &" + attr.ChildType() + "{}")
+}
+
+// whences maps the whence of an instance to the Go constant.
+var whences = map[string]string{
+	"seek_set": "io.SeekStart",
+	"seek_cur": "io.SeekCurrent",
+	"seek_end": "io.SeekEnd",
+}
 
+// ReadNode builds the body of the read function of an attribute.
+func (k *Type) ReadNode(attr Attribute) *ReadNode {
+	read := &ReadNode{}
 	if attr.If != "" {
-		buffer.WriteLine("if " + goExpr(attr.If, "") + "{")
-		defer buffer.WriteLine("}") // end if
+		read.If = goExpr(attr.If, attr.scope)
 	}
 
 	if attr.Value != "" {
 		// value instance
-		if attr.DataType() == "runtime.KSYDecoder" || strings.HasPrefix(attr.DataType(), "*") {
-			buffer.WriteLine(attrHolder + " = " + goExpr(attr.Value, ""))
-		} else {
-			buffer.WriteLine(attrHolder + " = " + attr.DataType() + "(" + goExpr(attr.Value, "") + ")")
+		read.Value = goExpr(attr.Value, attr.scope)
+		if dataType := attr.DataType(); !strings.HasPrefix(dataType, "*") {
+			read.Value = dataType + "(" + read.Value + ")"
 		}
-		buffer.WriteLine("return")
-		buffer.WriteLine("}")
-		return
+		return read
+	}
+
+	stream := "k"
+	if attr.IO != "" {
+		read.Stream = goExpr(attr.IO, attr.scope) + ".Stream()"
+		stream = "stream"
 	}
 
 	if attr.Pos != "" {
-		// save position
-		//buffer.WriteLine("pos" + attr.Name() + ", _ := k.Seek(0, io.SeekCurrent) // Cannot fail")
-		whence := "io.SeekCurrent"
-		whenceMap := map[string]string{
-			"seek_set": "io.SeekStart",
-			"seek_end": "io.SeekEnd",
-			"seek_cur": "io.SeekCurrent",
-		}
-		if val, ok := whenceMap[attr.Whence]; ok {
-			whence = val
+		whence, ok := whences[attr.Whence]
+		if !ok {
+			whence = "io.SeekStart"
 		}
-		if whence == "io.SeekCurrent" {
-			//buffer.WriteLine("k.Seek(0, io.SeekStart)")
-		}
-		//buffer.WriteLine("_, err = k.Seek(" + goExpr(attr.Pos, "int64") + ", " + whence + ")")
-		buffer.WriteLine("if " + errHolder + " != nil { return }")
-		// restore position
-		//defer buffer.WriteLine("k.Seek(pos" + attr.Name() + ", io.SeekStart)")
+		read.Seek = &SeekNode{Stream: stream, Pos: "int64(" + goExpr(attr.Pos, attr.scope) + ")", Whence: whence}
 	}
 
-	switch {
-	case attr.Repeat != "":
-		before := "true"
-		until := ""
-		fall := false
-		switch attr.Repeat {
-		case "expr":
-			if attr.RepeatExpr == "" {
-				panic("RepeatExpr is missing") // TODO: move to parsing
-			}
-			before = "index < int(" + goExpr(attr.RepeatExpr, "") + ")"
-			fall = true
-			fallthrough
-		case "until":
-			if !fall {
-				if attr.RepeatUntil == "" {
-					panic("RepeatUntil is missing") // TODO: move to parsing
-				}
-				until = goExprAttr(attr.RepeatUntil, "", attr.Name()+"[index]")
-			}
-			fallthrough
-		case "eos":
-			if attr.Value == "" {
-				buffer.WriteLine("var elem " + attr.ChildType())
-				attrHolder = "elem"
-			}
-
-			// slice
-			if strings.HasPrefix(attr.DataType(), "[]") {
-				//buffer.WriteLine(" ret = " + attr.DataType() + "{}")
-			}
-
-			buffer.WriteLine("for index := 0; " + before + "; index++ {")
-
-			buffer.WriteString(k.InitElem(attrHolder, errHolder, attr, attr.ChildType(), true))
-
-			// break on error
-			buffer.WriteLine("if " + errHolder + " != nil {")
-			buffer.WriteLine("if " + errHolder + " == io.EOF { " + errHolder + " = nil }")
-			buffer.WriteLine("break")
-			buffer.WriteLine("}")
+	if attr.Repeat != "" {
+		read.Repeat = k.RepeatNode(attr, stream)
+		return read
+	}
 
-			// add element
-			if strings.HasPrefix(attr.DataType(), "[]") {
-				buffer.WriteLine("ret = append(ret, " + attrHolder + ")")
-			} else {
-				buffer.WriteLine("k." + attr.Name() + "[index] = " + attrHolder)
-			}
+	if attr.Type.TypeSwitch.SwitchOn != "" {
+		read.Switch = k.SwitchNode(attr)
+	}
+	if attr.Type.CustomType {
+		read.New = attr.Type.New(attr.scope)
+	}
+	read.Elem = k.ElemNode("ret", stream, attr, attr.DataType())
+	if attr.Process != "" {
+		read.Process = k.ProcessNode(attr, "ret", false)
+	}
+	read.Valid = k.ValidNode("ret", stream, attr, attr.ChildType())
+	return read
+}
 
-			// break on repeat-until
-			if until != "" {
-				buffer.WriteLine("if " + until + "{break}")
-			}
+// RepeatNode builds the loop reading the elements of a repeated attribute.
+func (k *Type) RepeatNode(attr Attribute, stream string) *RepeatNode {
+	repeat := &RepeatNode{
+		ElemType: attr.ChildType(),
+		Cond:     "true",
+		Append:   strings.HasPrefix(attr.DataType(), "[]"),
+		Elem:     k.ElemNode("elem", stream, attr, attr.ChildType()),
+		Valid:    k.ValidNode("elem", stream, attr, attr.ChildType()),
+	}
+	switch attr.Repeat {
+	case "eos":
+		repeat.EOS = true
+	case "expr":
+		repeat.Cond = "index < int(" + goExpr(attr.RepeatExpr, attr.scope) + ")"
+	case "until":
+		// _ is the element just read, it is not added to the field yet
+		repeat.Until = goExprAttr(attr.RepeatUntil, attr.scope, "elem", attr.ChildType())
+	}
+	if attr.Type.CustomType && len(attr.Type.Args) > 0 {
+		repeat.New = attr.Type.Literal(attr.scope)
+	}
+	return repeat
+}
 
-			buffer.WriteLine("}")
-			buffer.WriteLine("return")
-			buffer.WriteLine("}")
-			return
-		}
-	case attr.Type.CustomType:
-		// custom struct
-		// init variable
-		// if attr.Size != "" {
-		// 	buffer.WriteLine(attr.Name() + "pos, _ := k.Seek(0, io.SeekCurrent) // Cannot fail")
-		// 	defer buffer.WriteLine("k.Seek(" + attr.Name() + "pos + " + goExpr(attr.Size, "int64") + ", io.SeekStart)")
-		// }
-		// buffer.WriteLine("k." + attr.Name() + " = &" + attr.DataType()[1:] + "{}")
-	case attr.Type.TypeSwitch.SwitchOn != "":
-		buffer.WriteLine("switch " + goExpr(attr.Type.TypeSwitch.SwitchOn, "") + " {")
-		for casevalue, casetype := range attr.Type.TypeSwitch.Cases {
-			if casevalue == "_" {
-				buffer.WriteLine("default:")
-			} else {
-				buffer.WriteLine("case " + goenum(casevalue, "int64") + ":")
-			}
-			buffer.WriteLine(attrHolder + " = &" + casetype.String() + "{}")
+// ValidNode builds the checks of the contents and the valid constraints of an
+// attribute on value. Attributes without constraints have no node.
+func (k *Type) ValidNode(value string, stream string, attr Attribute, dataType string) *ValidNode {
+	valid := attr.Valid
+	if valid.IsZero() && attr.Contents.Len() == 0 {
+		return nil
+	}
+	typed := func(expected string) string {
+		// untyped constants would become int in the error
+		if isNative(dataType) && dataType != "[]byte" && dataType != "string" && dataType != "bool" {
+			return dataType + "(" + expected + ")"
+		}
+		return expected
+	}
+	equal := func(expected string) string {
+		if dataType == "[]byte" {
+			return "bytes.Equal(" + value + ", " + expected + ")"
+		}
+		return value + " == " + expected
+	}
+	compare := func(op, expected string) string {
+		if dataType == "[]byte" {
+			return "bytes.Compare(" + value + ", " + expected + ") " + op + " 0"
 		}
-		buffer.WriteLine("}")
+		return value + " " + op + " " + expected
 	}
 
-	if attr.Type.CustomType && attr.Value == "" {
-		buffer.WriteLine(attrHolder + " = &" + attr.ChildType() + "{}")
+	node := &ValidNode{Stream: stream}
+	path := strconv.Quote(attr.path)
+	// start is the position of the value, see the validStart template
+	check := func(failed, kind, expected string) {
+		node.Checks = append(node.Checks, &ValidCheck{
+			Failed: failed,
+			Err:    "&runtime.ValidationError{Kind: " + kind + ", Path: " + path + ", Pos: start, Actual: " + value + ", Expected: " + expected + "}",
+		})
 	}
-	buffer.WriteString(k.InitElem(attrHolder, errHolder, attr, attr.DataType(), false))
 
-	if attr.Process != "" {
-		process := attr.Process
-		parts := strings.SplitN(process, "(", 2)
-		parameters := []string{}
-
-		cmd := parts[0]
-		if len(parts) > 1 {
-			parts[1] = strings.Trim(parts[1], "()")
-			for _, parameter := range strings.Split(parts[1], ",") {
-				parameter = strings.TrimSpace(parameter)
-				parameter = goExpr(parameter, "")
-				parameters = append(parameters, parameter)
-			}
+	if attr.Contents.Len() != 0 {
+		expected := attr.Contents.Bytes()
+		node.Checks = append(node.Checks, &ValidCheck{
+			Failed: "!bytes.Equal(" + value + ", " + expected + ")",
+			Err:    "&runtime.UnexpectedDataError{Path: " + path + ", Pos: start, Actual: " + value + ", Expected: " + expected + "}",
+		})
+	}
+	if valid.Eq != "" {
+		expected := typed(goExpr(valid.Eq, attr.scope))
+		check("!("+equal(expected)+")", "runtime.ValidationNotEqual", expected)
+	}
+	if valid.Min != "" {
+		expected := typed(goExpr(valid.Min, attr.scope))
+		check(compare("<", expected), "runtime.ValidationLessThan", expected)
+	}
+	if valid.Max != "" {
+		expected := typed(goExpr(valid.Max, attr.scope))
+		check(compare(">", expected), "runtime.ValidationGreaterThan", expected)
+	}
+	if len(valid.AnyOf) != 0 {
+		expected := make([]string, len(valid.AnyOf))
+		conds := make([]string, len(valid.AnyOf))
+		for i, v := range valid.AnyOf {
+			expected[i] = typed(goExpr(v, attr.scope))
+			conds[i] = equal(expected[i])
 		}
-		parameterList := strings.Join(parameters, ", ")
+		check("!("+strings.Join(conds, " || ")+")", "runtime.ValidationNotAnyOf", "[]interface{}{"+strings.Join(expected, ", ")+"}")
+	}
+	if valid.Expr != "" {
+		// _ is the value just read
+		check("!("+goExprAttr(valid.Expr, attr.scope, value, dataType)+")", "runtime.ValidationExprFailed", strconv.Quote(valid.Expr))
+	}
+	return node
+}
 
-		switch cmd {
-		case "xor":
-			list := "[]byte{byte(" + parameterList + ")}"
-			if strings.Contains(parameterList, ",") || (strings.HasPrefix(parameterList, "k") && getType(parameterList) != "uint8") {
-				list = "[]byte(" + parameterList + ")"
-			}
-			buffer.WriteLine(attrHolder + " = " + "runtime.ProcessXOR(" + attrHolder + ", " + list + ")")
-		case "rol":
-			buffer.WriteLine(attrHolder + " = " + "runtime.ProcessRotateLeft(" + attrHolder + ", int(" + parameterList + "))")
-		case "ror":
-			buffer.WriteLine(attrHolder + " = " + "runtime.ProcessRotateRight(" + attrHolder + ", int(" + parameterList + "))")
-		case "zlib":
-			buffer.WriteLine(attrHolder + ", err = " + "runtime.ProcessZlib(" + attrHolder + ")")
-		default:
-			buffer.WriteLine(attrHolder + " = " + goExpr(cmd, "")[2:len(goExpr(cmd, ""))-1] + attrHolder + ", " + parameterList + ")")
+// SwitchNode builds the choice of the user type of an attribute. The default
+// case comes last. Sized attributes without a default case keep the raw bytes
+// of unknown cases.
+func (k *Type) SwitchNode(attr Attribute) *SwitchNode {
+	node := &SwitchNode{On: goExpr(attr.Type.TypeSwitch.SwitchOn, attr.scope)}
+	for _, value := range attr.Type.TypeSwitch.CaseValues() {
+		casetype := attr.Type.TypeSwitch.Cases[value]
+		switchCase := &SwitchCase{New: casetype.New(attr.scope)}
+		if value != "_" {
+			switchCase.Value = goExpr(value, attr.scope)
 		}
+		node.Cases = append(node.Cases, switchCase)
 	}
-
-	if attr.Type.CustomType {
-		//buffer.WriteLine("k." + attr.Name() + " = &elem")
-	} else {
-		//buffer.WriteLine("k." + attr.Name() + " = elem")
+	if _, ok := attr.Type.TypeSwitch.Cases["_"]; !ok && (attr.Size != "" || attr.SizeEos == "true") {
+		node.Cases = append(node.Cases, &SwitchCase{New: "&runtime.RawBytes{}"})
 	}
-	buffer.WriteLine("return")
-	buffer.WriteLine("}")
-
-	return
+	return node
 }
 
-func (k *Type) String(typeName string, parent string, root string) string {
-	var buffer LineBuffer
-
-	if val, ok := endianess[k.Meta.Endian]; ok {
-		endian = val
+// ElemNode builds the read of a single value of an attribute from stream into
+// holder.
+func (k *Type) ElemNode(holder string, stream string, attr Attribute, dataType string) *ElemNode {
+	elem := &ElemNode{Holder: holder, Stream: stream}
+	switch {
+	case dataType == "[]byte" || dataType == "string":
+		elem.SizeCheck = sizeCheck(attr)
+		elem.Bytes = k.BytesNode(attr, dataType)
+	case isNative(dataType):
+		elem.Native = &NativeNode{ByteOrder: k.ByteOrder(func(endian string) string {
+			return toReadFunc(&attr, endian, k.Meta.BitEndian)
+		})}
+		if attr.Enum != "" {
+			// convert the raw integer to the enum type
+			elem.Native.Raw = attr.RawType()
+			elem.Native.Enum = dataType
+		}
+	default:
+		elem.User = &UserNode{
+			Ancestors: "k, k.Root()",
+			Err:       holder + ".DecodeErr",
+		}
+		// imported types are the root of their own tree
+		if attr.Type.Imported() {
+			elem.User.Ancestors = "k"
+		}
+		switch {
+		case attr.Size != "":
+			elem.SizeCheck = sizeCheck(attr)
+			elem.User.Substream = "ReadBytesAsReader(int64(" + goExpr(attr.Size, attr.scope) + "))"
+		case attr.SizeEos == "true":
+			elem.User.Substream = "ReadBytesFullAsReader()"
+		}
+		if dataType == "runtime.Decoder" {
+			elem.User.Err = holder + ".Err()"
+		}
 	}
+	return elem
+}
 
-	// print doc string
-	if k.Doc != "" {
-		buffer.WriteLine("/* " + strings.TrimSpace(k.Doc) + "*/")
+// sizeCheck returns the Go code of the size of attr, if it is an uint64. Sizes
+// above math.MaxInt64 would turn negative when converted to int64 and must be
+// checked with runtime.CheckSize first.
+func sizeCheck(attr Attribute) string {
+	if attr.Size == "" {
+		return ""
 	}
+	if t, err := checkExpr(attr.Size, attr.scope, ""); err != nil || t != "uint64" {
+		return ""
+	}
+	return goExpr(attr.Size, attr.scope)
+}
 
-	// print type start
-	buffer.WriteLine("type " + typeName + " struct {")
-	buffer.WriteLine("*runtime.TypeIO")
-
-	// print attrs and insts
-	for _, attr := range k.Seq {
-		attr.Category = "attribute"
-		buffer.WriteLine(attr.String())
+// BytesNode builds the read of a byte array or string. Sized data is stripped
+// of padding and cut at the terminator afterwards, strings are decoded with
+// the encoding of the attribute or type.
+func (k *Type) BytesNode(attr Attribute, dataType string) *BytesNode {
+	terminated := attr.Terminator != "" || attr.Type.Type == "strz"
+	term := "0"
+	if attr.Terminator != "" {
+		term = goExpr(attr.Terminator, attr.scope)
+	}
+	include := "false"
+	if attr.Include != "" {
+		include = goExpr(attr.Include, attr.scope)
+	}
+	consume := "true"
+	if attr.Consume != "" {
+		consume = goExpr(attr.Consume, attr.scope)
+	}
+	eosError := "true"
+	if attr.EosError != "" {
+		eosError = goExpr(attr.EosError, attr.scope)
 	}
 
-	for name, inst := range k.Instances {
-		inst.Category = "instance"
-		inst.ID = name
-		buffer.WriteLine(inst.String())
-		buffer.WriteLine(strcase.ToLowerCamel(inst.ID) + "Set bool")
+	node := &BytesNode{}
+	if attr.Size != "" || attr.SizeEos == "true" {
+		if attr.Pad != "" {
+			node.Pad = goExpr(attr.Pad, attr.scope)
+		}
+		if terminated {
+			node.Term = term
+			node.Include = include
+		}
+	}
+	if dataType == "string" {
+		node.Encoding = k.Encoding(attr)
 	}
 
-	// print type end
-	buffer.WriteLine("}")
+	switch {
+	case attr.Size != "":
+		node.Read = "ReadBytes(int64(" + goExpr(attr.Size, attr.scope) + "))"
+	case attr.SizeEos != "":
+		node.Read = "ReadBytesFull()"
+	case terminated:
+		node.Read = "ReadBytesTerm(byte(" + term + "), " + include + ", " + consume + ", " + eosError + ")"
+	default:
+		node.Read = "ReadBytesFull()"
+	}
+	return node
+}
 
-	// parent function
-	buffer.WriteLine("func (k *" + typeName + ") Parent() (*" + parent + ") {")
-	buffer.WriteLine("return k.ParentBase.(*" + parent + ")")
-	buffer.WriteLine("}")
+// ByteOrder returns the code created for the endianness of the type. With a
+// calculated endianness both variants are chosen from at runtime.
+func (k *Type) ByteOrder(code func(endian string) string) ByteOrder {
+	if !k.Meta.Endian.Calculated() {
+		return ByteOrder{Code: code(k.Meta.Endian.Default())}
+	}
+	order := ByteOrder{Code: code("le"), BE: code("be")}
+	if order.Code == order.BE {
+		order.BE = ""
+	}
+	return order
+}
 
-	// root function
-	buffer.WriteLine("func (k *" + typeName + ") Root() (*" + root + ") {")
-	buffer.WriteLine("return k.RootBase.(*" + root + ")")
-	buffer.WriteLine("}")
+// EndianNode builds the switch setting the calculated endianness of the type
+// while parsing.
+func (k *Type) EndianNode() *EndianNode {
+	node := &EndianNode{On: goExpr(k.Meta.Endian.SwitchOn, k.name)}
 
-	// decode function
-	buffer.WriteLine("func (k *" + typeName + ") Read(reader io.ReadSeeker, lazy bool, ancestors ...interface{}) {")
-	buffer.WriteLine("if k.TypeIO = runtime.NewTypeIO(reader, k, ancestors...); k.TypeIO.DecodeErr != nil {")
-	buffer.WriteLine("return")
-	buffer.WriteLine("}")
+	values := make([]string, 0, len(k.Meta.Endian.Cases))
+	for value := range k.Meta.Endian.Cases {
+		if value != "_" {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
 
-	for _, attr := range k.Seq {
-		buffer.WriteLine("if k." + attr.Name() + ", k.DecodeErr = " + k.CallAttr(attr) + "; k.DecodeErr != nil {")
-		buffer.WriteLine("return")
-		buffer.WriteLine("}")
+	for _, value := range values {
+		endianCase := &EndianCase{BigEndian: k.Meta.Endian.Cases[value] == "be"}
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			endianCase.Cond = "bytes.Equal(on, " + goExpr(value, k.name) + ")"
+		} else {
+			endianCase.Cond = "on == " + goExpr(value, k.name)
+		}
+		node.Cases = append(node.Cases, endianCase)
+	}
+	if endian, ok := k.Meta.Endian.Cases["_"]; ok {
+		node.Default = &EndianCase{BigEndian: endian == "be"}
 	}
-	buffer.WriteLine("return")
-	buffer.WriteLine("}")
+	return node
+}
 
-	for _, attr := range k.Seq {
-		buffer.WriteLine(k.InitAttr(attr, typeName))
+// Encoding returns the string encoding of an attribute, which defaults to the
+// encoding of the type.
+func (k *Type) Encoding(attr Attribute) string {
+	if attr.Encoding != "" {
+		return attr.Encoding
+	}
+	if k.Meta.Encoding != "" {
+		return k.Meta.Encoding
 	}
+	return "UTF-8"
+}
 
-	// create getter
-	for _, attr := range k.Seq {
-		buffer.WriteLine("func (k *" + typeName + ") " + strcase.ToCamel(attr.Name()) + "() (value " + attr.DataType() + ") {")
-		buffer.WriteLine("return " + "" + "k." + attr.Name())
-		buffer.WriteLine("}")
+// ProcessNode applies the process routine of the attribute to holder. The
+// inverse routine is used for writing, custom routines can not be inverted.
+func (k *Type) ProcessNode(attr Attribute, holder string, inverse bool) *ProcessNode {
+	parts := strings.SplitN(attr.Process, "(", 2)
+	args := []string{}
+	parameters := []string{}
+
+	cmd := parts[0]
+	if len(parts) > 1 {
+		args = splitArgs(strings.Trim(parts[1], "()"))
+		for _, arg := range args {
+			parameters = append(parameters, goExpr(arg, attr.scope))
+		}
 	}
+	parameterList := strings.Join(parameters, ", ")
 
-	// create inst getter
-	for name, inst := range k.Instances {
-		inst.ID = name
-		buffer.WriteLine("func (k *" + typeName + ") " + strcase.ToCamel(inst.Name()) + "() (value " + inst.DataType() + ") {")
-		buffer.WriteLine("if !k." + inst.Name() + "Set {")
-		init := k.InitAttr(inst, typeName)
-		if strings.Contains(init, "err") {
-			buffer.WriteLine("var err error")
+	if inverse {
+		switch cmd {
+		case "rol":
+			cmd = "ror"
+		case "ror":
+			cmd = "rol"
+		case "zlib":
+			return &ProcessNode{Assign: holder + ", err", Call: "runtime.UnprocessZlib(" + holder + ")"}
+		case "xor":
+		default:
+			return &ProcessNode{Assign: "err", Call: "fmt.Errorf(\"process %s can not be inverted\", \"" + cmd + "\")"}
 		}
-		buffer.WriteString(init)
-		buffer.WriteLine("k." + inst.Name() + "Set = true")
-		buffer.WriteLine("}")
-		buffer.WriteLine("return k." + inst.Name())
-		buffer.WriteLine("}")
 	}
 
-	// print subtypes (flattened)
-	for name, t := range k.Types {
-		typeStr := t.String(strcase.ToCamel(name), getParent(strcase.ToCamel(name)), root)
-		buffer.WriteLine(typeStr)
+	switch cmd {
+	case "xor":
+		list := "[]byte{byte(" + parameterList + ")}"
+		if strings.Contains(parameterList, ",") || (strings.HasPrefix(parameterList, "k") && getType(args[0], attr.scope) != "uint8") {
+			list = "[]byte(" + parameterList + ")"
+		}
+		return &ProcessNode{Assign: holder, Call: "runtime.ProcessXOR(" + holder + ", " + list + ")"}
+	case "rol":
+		return &ProcessNode{Assign: holder, Call: "runtime.ProcessRotateLeft(" + holder + ", int(" + parameterList + "))"}
+	case "ror":
+		return &ProcessNode{Assign: holder, Call: "runtime.ProcessRotateRight(" + holder + ", int(" + parameterList + "))"}
+	case "zlib":
+		return &ProcessNode{Assign: holder + ", err", Call: "runtime.ProcessZlib(" + holder + ")"}
+	default:
+		custom := goExpr(cmd, attr.scope)
+		return &ProcessNode{Assign: holder, Call: custom[2:len(custom)-1] + holder + ", " + parameterList + ")"}
 	}
+}
 
-	// print enums
-	for enum, values := range k.Enums {
-		buffer.WriteLine("var " + strcase.ToCamel(enum) + " = struct {")
-		for _, value := range values {
-			enumLiteral := toEnumLiteral(value)
-			buffer.WriteLine(enumLiteral.nameCamel + " " + getEnumType(enum))
+// EnumNode builds a named integer type for the enum with a const for every
+// value, ordered by value.
+func (k *Type) EnumNode(enum string) *EnumNode {
+	node := &EnumNode{Type: getEnumType(k.SubPath(enum))}
+	values := k.Enums[enum]
+	keys := make([]int, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	for _, key := range keys {
+		literal := toEnumLiteral(values[key])
+		value := &EnumValue{
+			Const:  node.Type + literal.nameCamel,
+			Value:  key,
+			Name:   literal.name,
+			OrigID: literal.origID,
 		}
-		buffer.WriteLine("}{")
-		for x, value := range values {
-			enumLiteral := toEnumLiteral(value)
-			buffer.WriteLine(enumLiteral.nameCamel + ": " + strconv.Itoa(x) + ",")
+		if literal.doc != "" {
+			for _, line := range strings.Split(literal.doc, "\n") {
+				value.Doc = append(value.Doc, strings.TrimRight(line, " "))
+			}
 		}
-		buffer.WriteLine("}")
+		node.Values = append(node.Values, value)
+		node.HasOrigID = node.HasOrigID || literal.origID != ""
 	}
-
-	return buffer.String()
+	return node
 }
 
 type EnumLiteral struct {
 	name      string
 	nameCamel string
 	doc       string
+	origID    string
 }
 
+// toEnumLiteral parses an enum value, which is either the plain id or a map
+// with id, doc and -orig-id.
 func toEnumLiteral(value interface{}) (ret *EnumLiteral) {
-	if m, ok := value.(map[string]string); ok {
-		ret = &EnumLiteral{name: m["id"], doc: m["doc"]}
-	} else {
-		ret = &EnumLiteral{name: fmt.Sprintf("%v", value)}
+	ret = &EnumLiteral{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			field := fmt.Sprintf("%v", val)
+			switch key {
+			case "id":
+				ret.name = field
+			case "doc":
+				ret.doc = strings.TrimSpace(field)
+			case "-orig-id":
+				ret.origID = field
+			}
+		}
+	case map[string]string:
+		ret.name, ret.doc, ret.origID = v["id"], strings.TrimSpace(v["doc"]), v["-orig-id"]
+	default:
+		ret.name = fmt.Sprintf("%v", value)
 	}
 	ret.nameCamel = strcase.ToCamel(ret.name)
 	return
 }
 
-func toReadFunc(attr *Attribute, defaultEndian string) (ret string) {
+func toReadFunc(attr *Attribute, defaultEndian, bitEndian string) (ret string) {
 	t := attr.Type.Type
-	switch t {
-	case "str":
-		if attr.Size != "" {
-			ret = fmt.Sprintf("ReadBytesString(uint16(%v))", goExpr(attr.Size, ""))
-		} else if attr.Contents.Len() > 0 {
-			ret = fmt.Sprintf("ReadBytesString(uint16(%v))", attr.Contents.Len())
-		} else {
-			ret = "ReadBytesFullString()"
-		}
-	case "strz":
-		if attr.Size != "" {
-			ret = fmt.Sprintf("ReadBytesString(uint16(%v))", goExpr(attr.Size, ""))
-		} else if attr.Contents.Len() > 0 {
-			ret = fmt.Sprintf("ReadBytesString(uint16(%v))", attr.Contents.Len())
-		} else {
-			ret = "ReadBytesFullString()"
-		}
-	case "":
-		if attr.Size != "" {
-			ret = fmt.Sprintf("ReadBytes(%v)", goExpr(attr.Size, ""))
-		} else if attr.Contents.Len() > 0 {
-			ret = fmt.Sprintf("ReadBytes(%v)", attr.Contents.Len())
-		} else {
-			ret = "ReadBytesFull()"
+	if bits, endian, ok := bitType(t, bitEndian); ok {
+		// bit sized integers do not depend on the byte order
+		if bits == 1 && attr.Enum == "" {
+			return "ReadB1" + endian + "Bool()"
 		}
+		return fmt.Sprintf("ReadBitsInt%v(%d)", strings.Title(endian), bits)
+	}
+	title := strings.ToUpper(t[0:1]) + t[1:]
+	switch {
+	case strings.HasSuffix(t, "le") || strings.HasSuffix(t, "be"):
+		ret = fmt.Sprintf("Read%v()", title)
+	case t == "u1" || t == "s1":
+		// single bytes have no byte order
+		ret = fmt.Sprintf("Read%v()", title)
 	default:
-		firstCharacter := t[0:1]
-		other := t[1:len(t)]
-		title := strings.ToUpper(firstCharacter) + other
-		suffix := ""
-		if strings.Contains(title, "B1") {
-			suffix = "Bool"
-		}
-		if strings.HasSuffix(t, "e") {
-			ret = fmt.Sprintf("Read%v%v()", title, suffix)
-		} else {
-			ret = fmt.Sprintf("Read%v%v%v()", title, defaultEndian, suffix)
-		}
+		ret = fmt.Sprintf("Read%v%v()", title, defaultEndian)
 	}
 	return
 }
@@ -1,6 +1,9 @@
 package main
 
-var kaitaiTypes map[string]string
+import (
+	"regexp"
+	"strconv"
+)
 
 func isNative(dataType string) bool {
 	nativeTypes := map[string]bool{
@@ -25,18 +28,26 @@ func isNative(dataType string) bool {
 	if _, ok := nativeTypes[dataType]; ok {
 		return true
 	}
-	return false
+	return isEnumType(dataType)
 }
 
-func addKaitaiType(kaitaiName, kaitaiType string) {
-	if val, ok := kaitaiTypes[kaitaiName]; !ok || val == "runtime.KSYDecoder" {
-		kaitaiTypes[kaitaiName] = kaitaiType
-	}
-}
+var bitTypePattern = regexp.MustCompile(`^b([0-9]+)(be|le)?$`)
 
-func getKaitaiType(kaitaiName string) string {
-	if kaitaiType, ok := kaitaiTypes[kaitaiName]; ok {
-		return kaitaiType
+// bitType returns the number of bits and the bit endianness of a bit sized
+// integer type like b3 or b12le. Types without endianness get bitEndian, which
+// defaults to big endian.
+func bitType(kaitaiType, bitEndian string) (bits int, endian string, ok bool) {
+	match := bitTypePattern.FindStringSubmatch(kaitaiType)
+	if match == nil {
+		return 0, "", false
+	}
+	bits, _ = strconv.Atoi(match[1])
+	endian = match[2]
+	if endian == "" {
+		endian = bitEndian
+	}
+	if endian == "" {
+		endian = "be"
 	}
-	return "runtime.KSYDecoder"
+	return bits, endian, true
 }
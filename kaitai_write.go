@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// WriteNode builds the body of the write function of a seq attribute, the
+// counterpart of ReadNode.
+func (k *Type) WriteNode(attr Attribute) *WriteNode {
+	write := &WriteNode{}
+	if attr.If != "" {
+		write.If = goExpr(attr.If, attr.scope)
+	}
+
+	value := "k." + strcase.ToCamel(attr.Name()) + "()"
+	if attr.Repeat != "" {
+		write.Repeat = value
+		write.Elem = k.WriteElemNode("elem", attr, attr.ChildType())
+	} else {
+		write.Elem = k.WriteElemNode(value, attr, attr.DataType())
+	}
+	return write
+}
+
+// WriteElemNode writes a single value of an attribute, the counterpart of
+// ElemNode.
+func (k *Type) WriteElemNode(value string, attr Attribute, dataType string) *WriteElemNode {
+	elem := &WriteElemNode{Value: value}
+	switch {
+	case attr.Contents.Len() != 0:
+		elem.Contents = attr.Contents.Bytes()
+	case dataType == "[]byte" || dataType == "string":
+		elem.Bytes = k.WriteBytesNode(attr, dataType)
+	case isNative(dataType):
+		if attr.Enum != "" {
+			value = attr.RawType() + "(" + value + ")"
+		}
+		order := k.ByteOrder(func(endian string) string {
+			return toWriteFunc(&attr, endian, k.Meta.BitEndian, value)
+		})
+		elem.Native = &order
+	default:
+		elem.User = &WriteUserNode{
+			ID:       attr.ID,
+			Encoder:  value,
+			NilCheck: strings.HasPrefix(dataType, "*") || dataType == "runtime.Decoder",
+		}
+		if dataType == "runtime.Decoder" {
+			elem.User.Encoder = value + ".(runtime.Encoder)"
+		}
+		if attr.Size != "" && !sizeFromIO(attr.Size) {
+			// fill up the sized substream
+			elem.User.Size = "int64(" + goExpr(attr.Size, attr.scope) + ")"
+		}
+	}
+	return elem
+}
+
+// WriteBytesNode writes a byte array or string, the counterpart of BytesNode.
+// Strings are encoded, the inverse process routine is applied and sized data
+// is terminated and padded.
+func (k *Type) WriteBytesNode(attr Attribute, dataType string) *WriteBytesNode {
+	terminated := attr.Terminator != "" || attr.Type.Type == "strz"
+	term := "0"
+	if attr.Terminator != "" {
+		term = goExpr(attr.Terminator, attr.scope)
+	}
+	include := "false"
+	if attr.Include != "" {
+		include = goExpr(attr.Include, attr.scope)
+	}
+	consume := "true"
+	if attr.Consume != "" {
+		consume = goExpr(attr.Consume, attr.scope)
+	}
+	pad := "0"
+	if attr.Pad != "" {
+		pad = goExpr(attr.Pad, attr.scope)
+	}
+
+	node := &WriteBytesNode{}
+	if dataType == "string" {
+		node.Encoding = k.Encoding(attr)
+	}
+	if attr.Process != "" {
+		node.Process = k.ProcessNode(attr, "raw", true)
+	}
+
+	switch {
+	case attr.Size != "" && !sizeFromIO(attr.Size):
+		if !terminated || include == "true" {
+			term = pad
+		}
+		node.Write = "w.WriteBytesLimit(raw, int64(" + goExpr(attr.Size, attr.scope) + "), byte(" + term + "), byte(" + pad + "))"
+	case terminated:
+		node.Write = "w.WriteBytesTerm(raw, byte(" + term + "), " + include + ", " + consume + ")"
+	default:
+		node.Write = "w.WriteBytes(raw)"
+	}
+	return node
+}
+
+// sizeFromIO returns true if the size expression depends on the stream, e.g.
+// _io.size - _io.pos. The decoded stream has no meaning when writing, so such
+// values are written with their own length.
+func sizeFromIO(size string) bool {
+	n, err := parseExpr(size)
+	return err == nil && usesIO(n)
+}
+
+// usesIO returns true if the expression n refers to an _io.
+func usesIO(n exprNode) bool {
+	switch n := n.(type) {
+	case *ident:
+		return n.Name == "_io"
+	case *member:
+		if n.Name == "_io" || usesIO(n.X) {
+			return true
+		}
+		for _, arg := range n.Args {
+			if usesIO(arg) {
+				return true
+			}
+		}
+	case *indexExpr:
+		return usesIO(n.X) || usesIO(n.Index)
+	case *castExpr:
+		return usesIO(n.X)
+	case *unaryExpr:
+		return usesIO(n.X)
+	case *binaryExpr:
+		return usesIO(n.X) || usesIO(n.Y)
+	case *ternaryExpr:
+		return usesIO(n.Cond) || usesIO(n.Then) || usesIO(n.Else)
+	case *parenExpr:
+		return usesIO(n.X)
+	}
+	return false
+}
+
+func toWriteFunc(attr *Attribute, defaultEndian, bitEndian string, value string) string {
+	read := toReadFunc(attr, defaultEndian, bitEndian)
+	name := "Write" + strings.TrimPrefix(read, "Read")
+	if strings.HasPrefix(name, "WriteBitsInt") {
+		// bit sized integers, e.g. WriteBitsIntBe(3, uint64(value))
+		return strings.TrimSuffix(name, ")") + ", uint64(" + value + "))"
+	}
+	return strings.TrimSuffix(name, "()") + "(" + value + ")"
+}
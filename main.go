@@ -16,7 +16,7 @@ import (
 	"github.com/kr/pretty"
 	"github.com/pkg/errors"
 	"golang.org/x/tools/imports"
-	yaml "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 func YAMLUnmarshal(name string, source []byte, m interface{}, path string, debug bool) error {
@@ -31,10 +31,25 @@ func YAMLUnmarshal(name string, source []byte, m interface{}, path string, debug
 	)
 }
 
+// generated holds the specs already written in this run.
+var generated = map[string]bool{}
+
+// timestamp adds the time of the generation to the header of generated files.
+var timestamp = true
+
 func createGoFile(ksyPath, pkg string, debug bool) error {
 	filename := path.Base(ksyPath)
 	dir := filepath.Dir(ksyPath)
 
+	absPath, err := filepath.Abs(ksyPath)
+	if err != nil {
+		return err
+	}
+	if generated[absPath] {
+		return nil
+	}
+	generated[absPath] = true
+
 	// setup logging
 	if debug {
 		logfile, err := os.Create(path.Join(dir, filename+".log"))
@@ -51,56 +66,121 @@ func createGoFile(ksyPath, pkg string, debug bool) error {
 	// start generation
 	log.Println("generate", ksyPath)
 
-	// read source
-	source, err := ioutil.ReadFile(ksyPath)
+	spec, err := loadSpec(ksyPath, debug)
 	if err != nil {
-		return errors.Wrap(err, "read source")
-	}
-
-	// parse generic
-	m := make(map[interface{}]interface{})
-	err = YAMLUnmarshal("generic", source, &m, path.Join(dir, filename), debug)
-	if err != nil {
-		return errors.Wrap(err, "parse generic yaml")
+		return err
 	}
 
 	// parse kaitai
-	kaitai := Type{}
+	kaitai := spec.Type
 	enumTypes = map[string]string{}
 	parents = map[string]string{}
-	kaitaiTypes = map[string]string{
-		"Itoa": "[]byte",
-		"len":  "int64",
-	}
-	err = YAMLUnmarshal("kaitai", source, &kaitai, path.Join(dir, filename), debug)
+	typeParams = map[string][]Attribute{}
+	importTypes = map[string]string{}
+	typeScopes = map[string]*Type{}
+	goImports, embedded, err := registerImports(spec)
 	if err != nil {
-		return errors.Wrap(err, "parse kaitai yaml")
+		return errors.Wrap(err, "resolve imports")
 	}
 	baseStruct := strcase.ToCamel(kaitai.Meta.ID)
 
+	setupEnums(&kaitai)
+	for _, e := range embedded {
+		setupEnums(&e.Type)
+	}
 	setupMap(&kaitai, baseStruct)
-	setupMap(&kaitai, baseStruct)
+	for _, e := range embedded {
+		setupMap(&e.Type, e.TypeName())
+	}
 
-	// write go code
-	var buffer LineBuffer
-	buffer.WriteLine("// file generated at " + time.Now().UTC().Format(time.RFC3339) + "\n")
-	buffer.WriteLine("package " + pkg)
-	buffer.WriteLine("import \"errors\"")
-	buffer.WriteLine("import \"fmt\"")
-	buffer.WriteLine("import \"io\"")
-	buffer.WriteLine("import \"bytes\"")
-	buffer.WriteLine("import \"github.com/go-ee/kaitaigo/runtime\"")
-	buffer.WriteLine(kaitai.String(baseStruct, baseStruct, baseStruct))
-
-	// format and add imports
-	formatted, err := imports.Process("", []byte(buffer.String()), nil)
+	// report all problems of the spec instead of generating broken code
+	if err := kaitai.Check(ksyPath); err != nil {
+		return err
+	}
+
+	// build and render the go code
+	header := "file generated by kaitaigo"
+	if timestamp {
+		header = "file generated at " + time.Now().UTC().Format(time.RFC3339)
+	}
+	file := &File{
+		Header:  header,
+		Package: pkg,
+		Imports: append([]string{"bytes", "errors", "fmt", "io", "github.com/go-ee/kaitaigo/runtime"}, goImports...),
+		Types:   kaitai.Node(baseStruct, baseStruct, baseStruct),
+	}
+	for _, e := range embedded {
+		file.Types = append(file.Types, e.Type.Node(e.TypeName(), e.TypeName(), e.TypeName())...)
+	}
+	code, err := file.Render()
 	if err != nil {
-		log.Printf("Format error (%s): %s", ksyPath, err)
-		formatted = []byte(buffer.String())
+		return errors.Wrap(err, "render go code")
+	}
+
+	// format and add imports, broken code is not written
+	formatted, err := imports.Process("", code, nil)
+	if err != nil {
+		return Diagnostics{{File: ksyPath, Line: 1, Column: 1, Msg: fmt.Sprintf("generated Go code is invalid: %v", err)}}
 	}
 	err = ioutil.WriteFile(path.Join(dir, filename+".go"), formatted, 0644)
+	if err != nil {
+		return errors.Wrap(err, "create go file")
+	}
 
-	return errors.Wrap(err, "create go file")
+	// every imported spec gets its own package as well
+	for _, imported := range spec.Imports {
+		if err = createGoFile(imported.Path, imported.Package(), debug); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerImports makes the root types of all imports of spec known to the
+// generator. It returns the Go import paths of imported packages and the
+// specs, that must be embedded into the generated file, because they import
+// spec themselves and Go does not allow import cycles.
+func registerImports(spec *Spec) (goImports []string, embedded []*Spec, err error) {
+	seen := map[*Spec]bool{spec: true}
+	var register func(s *Spec) error
+	register = func(s *Spec) error {
+		for _, imported := range s.Imports {
+			if seen[imported] {
+				continue
+			}
+			seen[imported] = true
+			name := imported.Type.Meta.ID
+			switch {
+			case imported.Dir() == spec.Dir():
+				// same package
+				addImportType(name, imported.TypeName())
+				addTypeParams(imported.TypeName(), imported.Type.Params)
+			case imported.Reaches(spec, map[*Spec]bool{}):
+				// circular import
+				addImportType(name, imported.TypeName())
+				embedded = append(embedded, imported)
+				if err := register(imported); err != nil {
+					return err
+				}
+			default:
+				importPath, err := goImportPath(imported.Dir())
+				if err != nil {
+					return err
+				}
+				goName := imported.Package() + "." + imported.TypeName()
+				addImportType(name, goName)
+				addTypeParams(goName, imported.Type.Params)
+				goImports = append(goImports, importPath)
+			}
+		}
+		return nil
+	}
+	err = register(spec)
+	if len(embedded) > 0 {
+		// the embedded specs use spec as an imported type as well
+		addImportType(spec.Type.Meta.ID, spec.TypeName())
+	}
+	return
 }
 
 func handleFile(filename, pkg string, debug bool) error {
@@ -112,7 +192,10 @@ func handleFile(filename, pkg string, debug bool) error {
 
 func main() {
 	debug := flag.Bool("debug", false, "debug output")
+	flag.Var(&includes, "I", "search path for imports, can be given multiple times")
+	flag.BoolVar(&timestamp, "timestamp", true, "add the generation time to the header, -timestamp=false creates identical files for identical specs")
 	flag.Parse()
+	failed := false
 	for _, filename := range flag.Args() {
 		var err error
 		if strings.HasSuffix(filename, "/...") {
@@ -122,16 +205,26 @@ func main() {
 				if err != nil {
 					return err
 				}
-				return handleFile(path, filepath.Base(filepath.Dir(absPath)), *debug)
+				// continue with the other files to report all problems
+				if err := handleFile(path, filepath.Base(filepath.Dir(absPath)), *debug); err != nil {
+					failed = true
+					log.Println(err)
+				}
+				return nil
 			})
 		} else {
-			absPath, err := filepath.Abs(filename)
+			var absPath string
+			absPath, err = filepath.Abs(filename)
 			if err == nil {
 				err = handleFile(filename, filepath.Base(filepath.Dir(absPath)), *debug)
 			}
 		}
 		if err != nil {
+			failed = true
 			log.Println(err)
 		}
 	}
+	if failed {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,13 @@
+package main
+
+var typeParams map[string][]Attribute
+
+func addTypeParams(typeName string, params []Attribute) {
+	if _, ok := typeParams[typeName]; !ok {
+		typeParams[typeName] = params
+	}
+}
+
+func getTypeParams(typeName string) []Attribute {
+	return typeParams[typeName]
+}
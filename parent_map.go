@@ -12,36 +12,51 @@ func addParent(typeName, parent string) {
 	}
 }
 
+// getParent returns the parent type of a user type or an empty string, if
+// the type is not used by another type.
 func getParent(typeName string) string {
-	if parent, ok := parents[typeName]; ok {
-		return parent
-	}
-	return "runtime.KSYDecoder"
+	return parents[typeName]
 }
 
 func prepare(attr Attribute, typeName string) {
-	addKaitaiType(strcase.ToCamel(attr.Name()), attr.DataType())
-	if attr.Enum != "" {
-		addEnumType(attr.Enum, attr.DataType())
-	}
 	addParent(strcase.ToCamel(attr.Type.Type), strcase.ToCamel(typeName))
 	if attr.Type.TypeSwitch.SwitchOn != "" {
-		for _, casetype := range attr.Type.TypeSwitch.Cases {
+		for _, value := range attr.Type.TypeSwitch.CaseValues() {
+			casetype := attr.Type.TypeSwitch.Cases[value]
 			addParent(strcase.ToCamel(casetype.Type), strcase.ToCamel(typeName))
 		}
 	}
 }
 
+// setupMap registers the parents, params and scopes of k and its subtypes.
+// Every attribute remembers the type it is declared in, to resolve the
+// identifiers of its expressions.
 func setupMap(k *Type, typeName string) {
-	for _, attr := range k.Seq {
-		prepare(attr, typeName)
+	k.name = strcase.ToCamel(typeName)
+	if k.root == "" {
+		k.root = k.name
+	}
+	addTypeScope(k)
+	addTypeParams(k.name, k.Params)
+	for i := range k.Params {
+		k.Params[i].scope = k.name
+	}
+	for i := range k.Seq {
+		k.Seq[i].scope = k.name
+		prepare(k.Seq[i], typeName)
 	}
-	for name, attr := range k.Instances {
+	for _, name := range k.InstanceNames() {
+		attr := k.Instances[name]
 		attr.ID = name
+		attr.scope = k.name
+		k.Instances[name] = attr
 		prepare(attr, typeName)
 	}
 
-	for name, t := range k.Types {
+	for _, name := range k.TypeNames() {
+		t := k.Types[name]
+		t.root = k.root
 		setupMap(&t, name)
+		k.Types[name] = t
 	}
 }
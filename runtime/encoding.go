@@ -0,0 +1,297 @@
+package runtime
+
+//go:generate go run gen_tables.go
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// encodingAliases maps alternative names to the normalized encoding names.
+var encodingAliases = map[string]string{
+	"USASCII":  "ASCII",
+	"UTF16":    "UTF16BE",
+	"UCS2LE":   "UTF16LE",
+	"UCS2BE":   "UTF16BE",
+	"UTF32":    "UTF32BE",
+	"SHIFTJIS": "SJIS",
+	"CP932":    "SJIS",
+	"IBM437":   "CP437",
+	"IBM850":   "CP850",
+	"IBM866":   "CP866",
+	"LATIN1":   "ISO88591",
+}
+
+// normalizeEncoding returns the encoding name in upper case without
+// separators, e.g. "utf-16le" becomes "UTF16LE".
+func normalizeEncoding(encoding string) string {
+	name := strings.ToUpper(encoding)
+	name = strings.NewReplacer("-", "", "_", "", " ", "").Replace(name)
+	if alias, ok := encodingAliases[name]; ok {
+		return alias
+	}
+	if strings.HasPrefix(name, "CP125") {
+		return "WINDOWS" + name[2:]
+	}
+	return name
+}
+
+// IsKnownEncoding returns true if DecodeString supports the encoding.
+func IsKnownEncoding(encoding string) bool {
+	switch name := normalizeEncoding(encoding); name {
+	case "", "ASCII", "UTF8", "UTF16LE", "UTF16BE", "UTF32LE", "UTF32BE", "SJIS", "ISO88591":
+		return true
+	default:
+		_, ok := codepages[name]
+		return ok
+	}
+}
+
+// DecodeString converts data in the given encoding to a UTF-8 string. Bytes
+// that are invalid in the encoding are replaced with U+FFFD.
+func DecodeString(data []byte, encoding string) (string, error) {
+	switch name := normalizeEncoding(encoding); name {
+	case "", "UTF8":
+		if !utf8.Valid(data) {
+			// converting to runes replaces every invalid byte
+			return string([]rune(string(data))), nil
+		}
+		return string(data), nil
+	case "ASCII":
+		return decodeSingleByte(data, nil), nil
+	case "ISO88591":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	case "UTF16LE", "UTF16BE":
+		return decodeUTF16(data, name == "UTF16BE"), nil
+	case "UTF32LE", "UTF32BE":
+		return decodeUTF32(data, name == "UTF32BE"), nil
+	case "SJIS":
+		return decodeSJIS(data), nil
+	default:
+		if table, ok := codepages[name]; ok {
+			return decodeSingleByte(data, table), nil
+		}
+		return "", fmt.Errorf("DecodeString: unknown encoding %q", encoding)
+	}
+}
+
+// decodeSingleByte decodes data with a table for the bytes 0x80 to 0xff. A
+// missing table accepts ASCII only.
+func decodeSingleByte(data []byte, table *[128]rune) string {
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			sb.WriteByte(b)
+		case table == nil:
+			sb.WriteRune(utf8.RuneError)
+		default:
+			sb.WriteRune(table[b-0x80])
+		}
+	}
+	return sb.String()
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	s := string(utf16.Decode(units))
+	if len(data)%2 != 0 {
+		s += string(utf8.RuneError)
+	}
+	return s
+}
+
+func decodeUTF32(data []byte, bigEndian bool) string {
+	var sb strings.Builder
+	for i := 0; i+4 <= len(data); i += 4 {
+		var r rune
+		if bigEndian {
+			r = rune(data[i])<<24 | rune(data[i+1])<<16 | rune(data[i+2])<<8 | rune(data[i+3])
+		} else {
+			r = rune(data[i+3])<<24 | rune(data[i+2])<<16 | rune(data[i+1])<<8 | rune(data[i])
+		}
+		if !utf8.ValidRune(r) {
+			r = utf8.RuneError
+		}
+		sb.WriteRune(r)
+	}
+	if len(data)%4 != 0 {
+		sb.WriteRune(utf8.RuneError)
+	}
+	return sb.String()
+}
+
+// decodeSJIS decodes Shift JIS with the characters of JIS X 0208.
+func decodeSJIS(data []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b < 0x80:
+			sb.WriteByte(b)
+		case b >= 0xa1 && b <= 0xdf:
+			// half-width katakana
+			sb.WriteRune(0xff61 + rune(b-0xa1))
+		case i+1 < len(data) && (b >= 0x81 && b <= 0x9f || b >= 0xe0 && b <= 0xef):
+			lead := int(b) - 0x81
+			if b >= 0xe0 {
+				lead = int(b) - 0xe0 + 0x1f
+			}
+			trail := int(data[i+1])
+			switch {
+			case trail >= 0x40 && trail <= 0x7e:
+				trail -= 0x40
+			case trail >= 0x80 && trail <= 0xfc:
+				trail -= 0x41
+			default:
+				sb.WriteRune(utf8.RuneError)
+				continue
+			}
+			sb.WriteRune(rune(sjisTable[lead*188+trail]))
+			i++
+		default:
+			sb.WriteRune(utf8.RuneError)
+		}
+	}
+	return sb.String()
+}
+
+// EncodeString converts the UTF-8 string s to the given encoding. Runes that
+// can not be represented in the encoding result in an error.
+func EncodeString(s string, encoding string) ([]byte, error) {
+	switch name := normalizeEncoding(encoding); name {
+	case "", "UTF8":
+		return []byte(s), nil
+	case "ASCII":
+		return encodeSingleByte(s, encoding, 0x80, nil)
+	case "ISO88591":
+		return encodeSingleByte(s, encoding, 0x100, nil)
+	case "UTF16LE", "UTF16BE":
+		units := utf16.Encode([]rune(s))
+		out := make([]byte, 0, 2*len(units))
+		for _, u := range units {
+			if name == "UTF16BE" {
+				out = append(out, byte(u>>8), byte(u))
+			} else {
+				out = append(out, byte(u), byte(u>>8))
+			}
+		}
+		return out, nil
+	case "UTF32LE", "UTF32BE":
+		out := make([]byte, 0, 4*len(s))
+		for _, r := range s {
+			if name == "UTF32BE" {
+				out = append(out, byte(r>>24), byte(r>>16), byte(r>>8), byte(r))
+			} else {
+				out = append(out, byte(r), byte(r>>8), byte(r>>16), byte(r>>24))
+			}
+		}
+		return out, nil
+	case "SJIS":
+		return encodeSJIS(s)
+	default:
+		if table, ok := codepages[name]; ok {
+			return encodeSingleByte(s, encoding, 0x80, table)
+		}
+		return nil, fmt.Errorf("EncodeString: unknown encoding %q", encoding)
+	}
+}
+
+// encodeSingleByte encodes runes below limit as is and the others with a
+// table for the bytes 0x80 to 0xff.
+func encodeSingleByte(s string, encoding string, limit rune, table *[128]rune) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r < limit {
+			out = append(out, byte(r))
+			continue
+		}
+		b, ok := byte(0), false
+		if table != nil {
+			b, ok = reverseTable(table)[r]
+		}
+		if !ok {
+			return nil, fmt.Errorf("EncodeString: %q can not be encoded in %s", r, encoding)
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+var (
+	reverseTablesMu sync.Mutex
+	reverseTables   = map[*[128]rune]map[rune]byte{}
+	sjisReverse     map[rune]uint16
+	sjisReverseOnce sync.Once
+)
+
+func reverseTable(table *[128]rune) map[rune]byte {
+	reverseTablesMu.Lock()
+	defer reverseTablesMu.Unlock()
+	reverse, ok := reverseTables[table]
+	if !ok {
+		reverse = make(map[rune]byte, len(table))
+		for i, r := range table {
+			if r != utf8.RuneError {
+				reverse[r] = byte(0x80 + i)
+			}
+		}
+		reverseTables[table] = reverse
+	}
+	return reverse
+}
+
+// encodeSJIS encodes s as Shift JIS with the characters of JIS X 0208.
+func encodeSJIS(s string) ([]byte, error) {
+	sjisReverseOnce.Do(func() {
+		sjisReverse = make(map[rune]uint16, len(sjisTable))
+		for i := len(sjisTable) - 1; i >= 0; i-- {
+			if r := rune(sjisTable[i]); r != 0 && r != utf8.RuneError {
+				sjisReverse[r] = uint16(i)
+			}
+		}
+	})
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case r >= 0xff61 && r <= 0xff9f:
+			// half-width katakana
+			out = append(out, byte(r-0xff61+0xa1))
+		default:
+			i, ok := sjisReverse[r]
+			if !ok {
+				return nil, fmt.Errorf("EncodeString: %q can not be encoded in SJIS", r)
+			}
+			lead, trail := int(i)/188, int(i)%188
+			if lead < 0x1f {
+				lead += 0x81
+			} else {
+				lead += 0xe0 - 0x1f
+			}
+			if trail < 0x3f {
+				trail += 0x40
+			} else {
+				trail += 0x41
+			}
+			out = append(out, byte(lead), byte(trail))
+		}
+	}
+	return out, nil
+}
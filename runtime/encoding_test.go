@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var encodingTests = []struct {
+	encoding string
+	data     []byte
+	s        string
+}{
+	{"ASCII", []byte("abc"), "abc"},
+	{"UTF-8", []byte{0x61, 0xc3, 0xa4}, "aä"},
+	{"", []byte("abc"), "abc"},
+	{"ISO-8859-1", []byte{0x41, 0xe4}, "Aä"},
+	{"latin1", []byte{0xff}, "ÿ"},
+	{"UTF-16LE", []byte{0xc4, 0x00, 0x41, 0x00}, "ÄA"},
+	{"UTF-16BE", []byte{0xd8, 0x3d, 0xde, 0x00}, "😀"},
+	{"UTF-32LE", []byte{0x00, 0xf6, 0x01, 0x00}, "😀"},
+	{"UTF-32BE", []byte{0x00, 0x00, 0x00, 0x41}, "A"},
+	{"SJIS", []byte{0x93, 0xfa, 0x96, 0x7b, 0xb1, 0x41}, "日本ｱA"},
+	{"Shift_JIS", []byte{0x81, 0x40}, "　"},
+	{"CP437", []byte{0x80, 0xdb}, "Ç█"},
+	{"IBM866", []byte{0x8f, 0xe0, 0xa8, 0xa2, 0xa5, 0xe2}, "Привет"},
+	{"KOI8-R", []byte{0xf0, 0xd2, 0xc9, 0xd7, 0xc5, 0xd4}, "Привет"},
+	{"windows-1251", []byte{0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2}, "Привет"},
+	{"windows-1252", []byte{0x80, 0x9f}, "€Ÿ"},
+	{"cp1250", []byte{0x8a, 0xb3}, "Šł"},
+	{"ISO-8859-7", []byte{0xd9, 0xf0}, "Ωπ"},
+	{"ISO-8859-15", []byte{0xa4, 0xb4}, "€Ž"},
+}
+
+func TestDecodeString(t *testing.T) {
+	for _, test := range encodingTests {
+		s, err := DecodeString(test.data, test.encoding)
+		assert.NoError(t, err, test.encoding)
+		assert.Equal(t, test.s, s, test.encoding)
+		assert.True(t, IsKnownEncoding(test.encoding), test.encoding)
+	}
+
+	_, err := DecodeString([]byte("abc"), "EBCDIC")
+	assert.EqualError(t, err, `DecodeString: unknown encoding "EBCDIC"`)
+	assert.False(t, IsKnownEncoding("EBCDIC"))
+}
+
+func TestEncodeString(t *testing.T) {
+	for _, test := range encodingTests {
+		data, err := EncodeString(test.s, test.encoding)
+		assert.NoError(t, err, test.encoding)
+		assert.Equal(t, test.data, data, test.encoding)
+	}
+
+	errors := []struct {
+		encoding string
+		s        string
+		msg      string
+	}{
+		{"ASCII", "aä", `EncodeString: 'ä' can not be encoded in ASCII`},
+		{"ISO-8859-1", "€", `EncodeString: '€' can not be encoded in ISO-8859-1`},
+		{"windows-1252", "Ж", `EncodeString: 'Ж' can not be encoded in windows-1252`},
+		{"SJIS", "€", `EncodeString: '€' can not be encoded in SJIS`},
+		{"EBCDIC", "abc", `EncodeString: unknown encoding "EBCDIC"`},
+	}
+	for _, test := range errors {
+		_, err := EncodeString(test.s, test.encoding)
+		assert.EqualError(t, err, test.msg, test.encoding)
+	}
+}
+
+func TestDecodeInvalidBytes(t *testing.T) {
+	tests := []struct {
+		encoding string
+		data     []byte
+		s        string
+	}{
+		{"ASCII", []byte{0x41, 0x80}, "A�"},
+		{"UTF-8", []byte{0xff, 0x61, 0xc3}, "�a�"},
+		{"UTF-16LE", []byte{0x00, 0xd8, 0x41, 0x00}, "�A"},
+		{"UTF-16LE", []byte{0x41, 0x00, 0x42}, "A�"},
+		{"UTF-32LE", []byte{0x00, 0x00, 0x11, 0x00}, "�"},
+		{"UTF-32BE", []byte{0x00, 0x00, 0x00, 0x41, 0x00}, "A�"},
+		{"windows-1252", []byte{0x81}, "�"},
+		{"ISO-8859-3", []byte{0xa5}, "�"},
+		// unmapped code, invalid trail byte, invalid lead byte and a lead byte
+		// at the end
+		{"SJIS", []byte{0x85, 0x40}, "�"},
+		{"SJIS", []byte{0x81, 0x7f}, "�\x7f"},
+		{"SJIS", []byte{0xf0, 0x40}, "�@"},
+		{"SJIS", []byte{0x41, 0x81}, "A�"},
+	}
+	for _, test := range tests {
+		s, err := DecodeString(test.data, test.encoding)
+		assert.NoError(t, err, test.encoding)
+		assert.Equal(t, test.s, s, "%s % x", test.encoding, test.data)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name := range codepages {
+		for b := 0; b < 0x100; b++ {
+			s, err := DecodeString([]byte{byte(b)}, name)
+			assert.NoError(t, err)
+			if s == string(utf8.RuneError) {
+				continue
+			}
+			data, err := EncodeString(s, name)
+			assert.NoError(t, err, "%s %#02x", name, b)
+			assert.Equal(t, []byte{byte(b)}, data, "%s %#02x", name, b)
+		}
+	}
+
+	for i, r := range sjisTable {
+		if r == utf8.RuneError {
+			continue
+		}
+		data, err := EncodeString(string(rune(r)), "SJIS")
+		if !assert.NoError(t, err, "%U", r) || !assert.Len(t, data, 2) {
+			continue
+		}
+		s, err := DecodeString(data, "SJIS")
+		assert.NoError(t, err)
+		assert.Equal(t, string(rune(r)), s, "% x", data)
+		assert.Equal(t, i, sjisIndex(data[0], data[1]), "%U", r)
+	}
+}
+
+// sjisIndex returns the index of the double byte character lead, trail in
+// sjisTable.
+func sjisIndex(lead, trail byte) int {
+	row := int(lead) - 0x81
+	if lead >= 0xe0 {
+		row = int(lead) - 0xe0 + 0x1f
+	}
+	col := int(trail) - 0x40
+	if trail >= 0x80 {
+		col = int(trail) - 0x41
+	}
+	return row*188 + col
+}
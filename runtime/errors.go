@@ -0,0 +1,154 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationKind is the kind of valid constraint a value violates.
+type ValidationKind int
+
+const (
+	// ValidationNotEqual is the violation of eq.
+	ValidationNotEqual ValidationKind = iota + 1
+	// ValidationLessThan is the violation of min.
+	ValidationLessThan
+	// ValidationGreaterThan is the violation of max.
+	ValidationGreaterThan
+	// ValidationNotAnyOf is the violation of any-of.
+	ValidationNotAnyOf
+	// ValidationExprFailed is the violation of expr.
+	ValidationExprFailed
+)
+
+func (k ValidationKind) String() string {
+	switch k {
+	case ValidationNotEqual:
+		return "not equal"
+	case ValidationLessThan:
+		return "less than"
+	case ValidationGreaterThan:
+		return "greater than"
+	case ValidationNotAnyOf:
+		return "not any of"
+	case ValidationExprFailed:
+		return "expr failed"
+	}
+	return fmt.Sprintf("ValidationKind(%d)", int(k))
+}
+
+// ValidationError is returned if a parsed value violates a valid constraint
+// of the spec. Path is the path of the attribute in the spec, e.g.
+// "/types/header/seq/0", and Pos the offset of the value in its stream.
+// Expected holds the value of eq, min or max, the values of any-of or the
+// expression of expr.
+type ValidationError struct {
+	Kind     ValidationKind
+	Path     string
+	Pos      int64
+	Actual   interface{}
+	Expected interface{}
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s at pos %d: validation failed, %s: expected %v, got %v", e.Path, e.Pos, e.Kind, e.Expected, e.Actual)
+}
+
+// UnexpectedDataError is returned if the bytes of an attribute with contents
+// differ from the expected contents. Path is the path of the attribute in the
+// spec and Pos the offset of the bytes in their stream.
+type UnexpectedDataError struct {
+	Path     string
+	Pos      int64
+	Actual   []byte
+	Expected []byte
+}
+
+func (e *UnexpectedDataError) Error() string {
+	return fmt.Sprintf("%s at pos %d: unexpected data, expected % x, got % x", e.Path, e.Pos, e.Expected, e.Actual)
+}
+
+// ErrEmptyArray is the cause of an ExprError of first, last, min or max of an
+// empty array.
+var ErrEmptyArray = errors.New("empty array")
+
+// ExprError is returned if an expression of the spec can not be evaluated,
+// e.g. min of an empty array or to_i of a string, that is not a number.
+// Method is the Kaitai method, that failed.
+type ExprError struct {
+	Method string
+	Err    error
+}
+
+func (e *ExprError) Error() string {
+	return e.Method + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause of the error.
+func (e *ExprError) Unwrap() error {
+	return e.Err
+}
+
+// RecoverExprError returns the ExprError r, that was recovered from a panic
+// of an expression helper, or err if nothing was recovered. Other panics are
+// continued. The generated read functions defer it, because expressions are
+// evaluated inline.
+func RecoverExprError(r interface{}, err error) error {
+	if r == nil {
+		return err
+	}
+	if exprErr, ok := r.(*ExprError); ok {
+		return exprErr
+	}
+	panic(r)
+}
+
+// ParseError is returned if an attribute can not be parsed. Type is the Go
+// type of the attribute, Path the path of the attribute below the decoded
+// type, e.g. "header.entries[3].name", and Pos the position of the stream
+// when the error occurred. Err is the cause, e.g. io.ErrUnexpectedEOF or a
+// ValidationError.
+type ParseError struct {
+	Type string
+	Path string
+	Pos  int64
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing %s of %s at pos %d: %v", e.Path, e.Type, e.Pos, e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// WrapParseError prepends path to the path of err, if it is a ParseError of
+// a nested attribute. Other errors are wrapped into a ParseError of the
+// attribute path of typeName at the current position of stream.
+func WrapParseError(err error, typeName, path string, stream *Stream) error {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		// the path of an error of the nested type itself is empty
+		if parseErr.Path != "" && !strings.HasPrefix(parseErr.Path, "[") {
+			path += "."
+		}
+		parseErr.Path = path + parseErr.Path
+		if parseErr.Type == "" {
+			parseErr.Type = typeName
+		}
+		return parseErr
+	}
+	parseErr = &ParseError{Type: typeName, Path: path, Err: err}
+	if stream != nil {
+		parseErr.Pos, _ = stream.Pos()
+	}
+	return parseErr
+}
+
+// IndexParseError adds the index of a repeated element to the path of err.
+func IndexParseError(err error, index int, stream *Stream) error {
+	return WrapParseError(err, "", fmt.Sprintf("[%d]", index), stream)
+}
@@ -0,0 +1,159 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Mod returns a modulo b like Kaitai Struct, the result has the sign of b,
+// e.g. -2 % 8 = 6. Go's % truncates and would return -2.
+func Mod(a, b int64) int64 {
+	r := a % b
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+	return r
+}
+
+// ModFloat is Mod for floating point numbers.
+func ModFloat(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+	return r
+}
+
+// First returns the first element of an array or slice. Empty arrays panic
+// with an ExprError, see RecoverExprError.
+func First(array interface{}) interface{} {
+	return nonEmpty(array, "first").Index(0).Interface()
+}
+
+// Last returns the last element of an array or slice, see First.
+func Last(array interface{}) interface{} {
+	v := nonEmpty(array, "last")
+	return v.Index(v.Len() - 1).Interface()
+}
+
+// Min returns the smallest element of an array or slice of numbers, strings
+// or byte arrays. Byte arrays are compared lexicographically. Empty arrays
+// panic with an ExprError, see RecoverExprError.
+func Min(array interface{}) interface{} {
+	return extreme(nonEmpty(array, "min"), -1, "min")
+}
+
+// Max returns the largest element of an array or slice, see Min.
+func Max(array interface{}) interface{} {
+	return extreme(nonEmpty(array, "max"), 1, "max")
+}
+
+// nonEmpty returns the value of array. It panics with an ExprError of method,
+// if array is empty.
+func nonEmpty(array interface{}, method string) reflect.Value {
+	v := reflect.ValueOf(array)
+	if v.Len() == 0 {
+		panic(&ExprError{Method: method, Err: ErrEmptyArray})
+	}
+	return v
+}
+
+func extreme(v reflect.Value, sign int, method string) interface{} {
+	m := v.Index(0)
+	for i := 1; i < v.Len(); i++ {
+		if e := v.Index(i); compare(e, m, method) == sign {
+			m = e
+		}
+	}
+	return m.Interface()
+}
+
+// compare returns -1, 0 or 1 if a is less than, equal to or greater than b.
+// It panics with an ExprError of method, if a can not be compared.
+func compare(a, b reflect.Value, method string) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sign(a.Int() < b.Int(), a.Int() > b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return sign(a.Uint() < b.Uint(), a.Uint() > b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return sign(a.Float() < b.Float(), a.Float() > b.Float())
+	case reflect.String:
+		return sign(a.String() < b.String(), a.String() > b.String())
+	case reflect.Slice:
+		if a.Type().Elem().Kind() == reflect.Uint8 {
+			return bytes.Compare(a.Bytes(), b.Bytes())
+		}
+	}
+	panic(&ExprError{Method: method, Err: fmt.Errorf("can not compare %s", a.Type())})
+}
+
+func sign(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// StringReverse returns s with its characters in reverse order.
+func StringReverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// Substring returns the characters of s from index from up to but not
+// including to. Indexes are limited to the length of s.
+func Substring(s string, from, to int) string {
+	runes := []rune(s)
+	clamp := func(i int) int {
+		switch {
+		case i < 0:
+			return 0
+		case i > len(runes):
+			return len(runes)
+		}
+		return i
+	}
+	from, to = clamp(from), clamp(to)
+	if from >= to {
+		return ""
+	}
+	return string(runes[from:to])
+}
+
+// StringToInt parses s as an integer in the given radix, e.g. 16 for "ff".
+// If s is not a number, it panics with an ExprError, see RecoverExprError.
+func StringToInt(s string, radix int) int64 {
+	i, err := strconv.ParseInt(s, radix, 64)
+	if err != nil {
+		panic(&ExprError{Method: "to_i", Err: err})
+	}
+	return i
+}
+
+// BytesToString decodes data in the given encoding. Unknown encodings panic
+// with an ExprError, see RecoverExprError.
+func BytesToString(data []byte, encoding string) string {
+	s, err := DecodeString(data, encoding)
+	if err != nil {
+		panic(&ExprError{Method: "to_s", Err: err})
+	}
+	return s
+}
+
+// BoolToInt returns 1 for true and 0 for false.
+func BoolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
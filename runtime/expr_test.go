@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMod(t *testing.T) {
+	assert.EqualValues(t, 6, Mod(-2, 8))
+	assert.EqualValues(t, 2, Mod(2, 8))
+	assert.EqualValues(t, 0, Mod(-8, 8))
+	assert.EqualValues(t, -6, Mod(2, -8))
+	assert.EqualValues(t, 4, Mod(-9837, 13))
+	assert.InDelta(t, 6.5, ModFloat(-1.5, 8), 1e-9)
+	assert.InDelta(t, 1.5, ModFloat(1.5, 8), 1e-9)
+}
+
+func TestMinMax(t *testing.T) {
+	aint := [4]uint32{7657765, 49185, 1123362332, 16272640}
+	assert.Equal(t, uint32(49185), Min(aint))
+	assert.Equal(t, uint32(1123362332), Max(aint))
+
+	assert.Equal(t, int8(-3), Min([]int8{5, -3, 0}))
+	assert.Equal(t, -8.75, Min([]float64{-1.5, -8.75, 2}))
+	assert.Equal(t, 2.0, Max([]float64{-1.5, -8.75, 2}))
+	assert.Equal(t, "bar", Min([]string{"foo", "bar", "baz"}))
+	assert.Equal(t, "foo", Max([]string{"foo", "bar", "baz"}))
+	assert.Equal(t, []byte{1, 2}, Min([][]byte{{1, 3}, {1, 2}, {1, 2, 0}}))
+	assert.Equal(t, []byte{1, 3}, Max([][]byte{{1, 3}, {1, 2}, {1, 2, 0}}))
+
+	assert.EqualError(t, exprPanic(func() { Min([]int{}) }), "min: empty array")
+	assert.EqualError(t, exprPanic(func() { Max([0]int{}) }), "max: empty array")
+	assert.EqualError(t, exprPanic(func() { Max([]struct{}{{}, {}}) }), "max: can not compare struct {}")
+}
+
+func TestFirstLast(t *testing.T) {
+	assert.Equal(t, uint32(7), First([]uint32{7, 8, 9}))
+	assert.Equal(t, uint32(9), Last([]uint32{7, 8, 9}))
+	assert.Equal(t, "a", Last([1]string{"a"}))
+	assert.EqualError(t, exprPanic(func() { First([]byte(nil)) }), "first: empty array")
+	assert.EqualError(t, exprPanic(func() { Last([]string{}) }), "last: empty array")
+}
+
+// exprPanic returns the ExprError expr panics with.
+func exprPanic(expr func()) (err error) {
+	defer func() {
+		err = RecoverExprError(recover(), err)
+	}()
+	expr()
+	return
+}
+
+func TestRecoverExprError(t *testing.T) {
+	err := exprPanic(func() { Min([]int64{}) })
+	assert.True(t, errors.Is(err, ErrEmptyArray), "%v", err)
+
+	err = exprPanic(func() { StringToInt("12x", 10) })
+	assert.True(t, errors.Is(err, strconv.ErrSyntax), "%v", err)
+	assert.EqualError(t, err, `to_i: strconv.ParseInt: parsing "12x": invalid syntax`)
+
+	assert.NoError(t, exprPanic(func() { StringToInt("12", 10) }))
+
+	// other panics are no expression errors
+	assert.Panics(t, func() { _ = exprPanic(func() { panic("bug") }) })
+}
+
+func TestStringMethods(t *testing.T) {
+	assert.Equal(t, "cba", StringReverse("abc"))
+	assert.Equal(t, "ßäö", StringReverse("öäß"))
+	assert.Equal(t, "", StringReverse(""))
+
+	assert.Equal(t, "bc", Substring("abcd", 1, 3))
+	assert.Equal(t, "äö", Substring("üäöß", 1, 3))
+	assert.Equal(t, "cd", Substring("abcd", 2, 10))
+	assert.Equal(t, "", Substring("abcd", 3, 1))
+
+	assert.EqualValues(t, 123, StringToInt("123", 10))
+	assert.EqualValues(t, -255, StringToInt("-ff", 16))
+	assert.EqualValues(t, 5, StringToInt("101", 2))
+	assert.Error(t, exprPanic(func() { StringToInt("12x", 10) }))
+}
+
+func TestConversions(t *testing.T) {
+	assert.Equal(t, "AB", BytesToString([]byte("AB"), "ASCII"))
+	assert.Equal(t, "Ä", BytesToString([]byte{0xc4, 0x00}, "UTF-16LE"))
+	assert.EqualError(t, exprPanic(func() { BytesToString([]byte("AB"), "EBCDIC") }), `to_s: DecodeString: unknown encoding "EBCDIC"`)
+	assert.EqualValues(t, 1, BoolToInt(true))
+	assert.EqualValues(t, 0, BoolToInt(false))
+}
@@ -0,0 +1,198 @@
+//go:build ignore
+// +build ignore
+
+// gen_tables generates codepages.go and sjis_table.go from the mapping files
+// of the Unicode Consortium at https://www.unicode.org/Public/MAPPINGS/. The
+// files are provided by Unicode, Inc. under the Unicode License, see
+// https://www.unicode.org/license.txt. Run it with go generate.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// codepages are the single-byte encodings with the path of their mapping
+// file, in the order of codepages.go.
+var codepages = []struct {
+	name string
+	path string
+}{
+	{"CP437", "VENDORS/MICSFT/PC/CP437.TXT"},
+	{"CP850", "VENDORS/MICSFT/PC/CP850.TXT"},
+	{"CP866", "VENDORS/MICSFT/PC/CP866.TXT"},
+	{"ISO88592", "ISO8859/8859-2.TXT"},
+	{"ISO88593", "ISO8859/8859-3.TXT"},
+	{"ISO88594", "ISO8859/8859-4.TXT"},
+	{"ISO88595", "ISO8859/8859-5.TXT"},
+	{"ISO88596", "ISO8859/8859-6.TXT"},
+	{"ISO88597", "ISO8859/8859-7.TXT"},
+	{"ISO88598", "ISO8859/8859-8.TXT"},
+	{"ISO88599", "ISO8859/8859-9.TXT"},
+	{"ISO885910", "ISO8859/8859-10.TXT"},
+	{"ISO885911", "ISO8859/8859-11.TXT"},
+	{"ISO885913", "ISO8859/8859-13.TXT"},
+	{"ISO885914", "ISO8859/8859-14.TXT"},
+	{"ISO885915", "ISO8859/8859-15.TXT"},
+	{"ISO885916", "ISO8859/8859-16.TXT"},
+	{"WINDOWS1250", "VENDORS/MICSFT/WINDOWS/CP1250.TXT"},
+	{"WINDOWS1251", "VENDORS/MICSFT/WINDOWS/CP1251.TXT"},
+	{"WINDOWS1252", "VENDORS/MICSFT/WINDOWS/CP1252.TXT"},
+	{"WINDOWS1253", "VENDORS/MICSFT/WINDOWS/CP1253.TXT"},
+	{"WINDOWS1254", "VENDORS/MICSFT/WINDOWS/CP1254.TXT"},
+	{"WINDOWS1255", "VENDORS/MICSFT/WINDOWS/CP1255.TXT"},
+	{"WINDOWS1256", "VENDORS/MICSFT/WINDOWS/CP1256.TXT"},
+	{"WINDOWS1257", "VENDORS/MICSFT/WINDOWS/CP1257.TXT"},
+	{"WINDOWS1258", "VENDORS/MICSFT/WINDOWS/CP1258.TXT"},
+	{"KOI8R", "VENDORS/MISC/KOI8-R.TXT"},
+	{"KOI8U", "VENDORS/MISC/KOI8-U.TXT"},
+}
+
+// sjisPath is the mapping of JIS X 0208, its first column is the Shift JIS
+// code.
+const sjisPath = "OBSOLETE/EASTASIA/JIS/JIS0208.TXT"
+
+var base = flag.String("base", "https://www.unicode.org/Public/MAPPINGS", "base URL of the mapping files")
+
+func main() {
+	flag.Parse()
+
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated by gen_tables.go from the Unicode mapping files; DO NOT EDIT.
+
+package runtime
+
+// codepages maps the normalized name of a single-byte encoding to the runes
+// of the bytes 0x80 to 0xff. The bytes 0x00 to 0x7f are ASCII. The runes are
+// taken from the Unicode mapping tables of the code pages, bytes without a
+// mapping are U+FFFD.
+var codepages = map[string]*[128]rune{
+`)
+	for _, cp := range codepages {
+		var table [128]uint16
+		for i := range table {
+			table[i] = 0xfffd
+		}
+		for code, r := range mappings(cp.path, 1) {
+			if code >= 0x80 && code <= 0xff {
+				table[code-0x80] = r
+			}
+		}
+		fmt.Fprintf(&buf, "%q: {\n", cp.name)
+		writeRunes(&buf, table[:], 8)
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}\n")
+	write("codepages.go", buf.Bytes())
+
+	// lead bytes 0x81 to 0x9f and 0xe0 to 0xef, trail bytes 0x40 to 0x7e and
+	// 0x80 to 0xfc
+	var sjis [47 * 188]uint16
+	for i := range sjis {
+		sjis[i] = 0xfffd
+	}
+	for code, r := range mappings(sjisPath, 2) {
+		lead, trail := int(code>>8), int(code&0xff)
+		row := lead - 0x81
+		if lead >= 0xe0 {
+			row = lead - 0xe0 + 0x1f
+		}
+		col := trail - 0x40
+		if trail >= 0x80 {
+			col = trail - 0x41
+		}
+		// REVERSE SOLIDUS is the single byte 0x5c, the double byte
+		// character is the full width form
+		if code == 0x815f && r == 0x5c {
+			r = 0xff3c
+		}
+		sjis[row*188+col] = r
+	}
+	buf.Reset()
+	fmt.Fprintf(&buf, `// Code generated by gen_tables.go from the Unicode mapping files; DO NOT EDIT.
+
+package runtime
+
+// sjisTable maps the JIS X 0208 double byte characters of Shift JIS to runes.
+// The lead bytes 0x81 to 0x9f and 0xe0 to 0xef are followed by 188 trail
+// bytes each, 0x40 to 0x7e and 0x80 to 0xfc. The runes are taken from the
+// Unicode mapping table of JIS X 0208, codes without a mapping are U+FFFD.
+var sjisTable = [%d]uint16{
+`, len(sjis))
+	writeRunes(&buf, sjis[:], 12)
+	buf.WriteString("}\n")
+	write("sjis_table.go", buf.Bytes())
+}
+
+// mappings fetches the mapping file at path and returns the code in the first
+// column mapped to the Unicode code point in the column with the index
+// unicodeColumn. Codes without a mapping are left out.
+func mappings(path string, unicodeColumn int) map[uint16]uint16 {
+	url := *base + "/" + path
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("%s: %s", url, resp.Status)
+	}
+
+	ret := map[uint16]uint16{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) <= unicodeColumn {
+			// empty, comment or undefined
+			continue
+		}
+		code, err := strconv.ParseUint(fields[0], 0, 16)
+		if err != nil {
+			log.Fatalf("%s: %v", url, err)
+		}
+		r, err := strconv.ParseUint(fields[unicodeColumn], 0, 16)
+		if err != nil {
+			log.Fatalf("%s: %v", url, err)
+		}
+		ret[uint16(code)] = uint16(r)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return ret
+}
+
+// writeRunes writes the runes as hex literals, perLine per line.
+func writeRunes(buf *bytes.Buffer, runes []uint16, perLine int) {
+	for i, r := range runes {
+		fmt.Fprintf(buf, "0x%04x,", r)
+		if (i+1)%perLine == 0 || i == len(runes)-1 {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString(" ")
+		}
+	}
+}
+
+// write formats src and writes it to the file name.
+func write(name string, src []byte) {
+	src, err := format.Source(src)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	if err := ioutil.WriteFile(name, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
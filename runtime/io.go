@@ -0,0 +1,63 @@
+package runtime
+
+import "errors"
+
+// ErrNoStream is returned if an instance is read from the io of an object,
+// that has no stream, e.g. because it was not decoded or is still deferred.
+var ErrNoStream = errors.New("object has no stream")
+
+// IO is the stream of an object as used in expressions, e.g. _io.pos or
+// _root._io. Expressions can not fail, so errors of the stream result in zero
+// values. An empty IO is at its end.
+type IO struct {
+	stream *Stream
+}
+
+// IO returns the stream for expressions.
+func (k *Stream) IO() IO {
+	return IO{stream: k}
+}
+
+// IO returns the stream of the type for expressions. Types that were not
+// decoded have an empty stream.
+func (k *TypeIO) IO() IO {
+	if k == nil {
+		return IO{}
+	}
+	return IO{stream: k.Stream}
+}
+
+// Stream returns the underlying stream, e.g. to read an instance from it.
+func (k IO) Stream() (*Stream, error) {
+	if k.stream == nil {
+		return nil, ErrNoStream
+	}
+	return k.stream, nil
+}
+
+// Pos returns the current position of the stream.
+func (k IO) Pos() int64 {
+	if k.stream == nil {
+		return 0
+	}
+	pos, _ := k.stream.Pos()
+	return pos
+}
+
+// Size returns the number of bytes of the stream.
+func (k IO) Size() int64 {
+	if k.stream == nil {
+		return 0
+	}
+	size, _ := k.stream.Size()
+	return size
+}
+
+// EOF returns true when the end of the stream is reached.
+func (k IO) EOF() bool {
+	if k.stream == nil {
+		return true
+	}
+	eof, _ := k.stream.EOF()
+	return eof
+}
@@ -45,3 +45,17 @@ func ProcessZlib(in []byte) (out []byte, err error) {
 
 	return ioutil.ReadAll(r)
 }
+
+// UnprocessZlib compresses the given bytes as specified in RFC 1950, the
+// inverse of ProcessZlib.
+func UnprocessZlib(in []byte) (out []byte, err error) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err = w.Write(in); err != nil {
+		return
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	return b.Bytes(), nil
+}
@@ -1,13 +1,41 @@
 package runtime
 
 import (
-	"bytes"
+	"fmt"
 	"github.com/pkg/errors"
 	"io"
+	"io/ioutil"
 )
 
+// ErrUndecidedEndianness is returned if none of the cases of a calculated
+// endianness matches.
+var ErrUndecidedEndianness = errors.New("unable to decide on endianness")
+
 type Decoder interface {
 	Read(reader io.ReadSeeker, lazy bool, ancestors ...interface{})
+	Err() error
+}
+
+// RawBytes is the value of a sized field with a type switch, if none of the
+// cases matches. It holds the bytes of the substream.
+type RawBytes struct {
+	Data      []byte
+	DecodeErr error
+}
+
+// Read reads all bytes of reader.
+func (k *RawBytes) Read(reader io.ReadSeeker, lazy bool, ancestors ...interface{}) {
+	k.Data, k.DecodeErr = ioutil.ReadAll(reader)
+}
+
+// Err returns the error that occurred while reading.
+func (k *RawBytes) Err() error {
+	return k.DecodeErr
+}
+
+// Write writes the bytes unchanged.
+func (k *RawBytes) Write(w *Writer) error {
+	return w.WriteBytes(k.Data)
 }
 
 type Meta struct {
@@ -17,6 +45,9 @@ type TypeIO struct {
 	*Stream
 	Decoded    bool
 	DecodeErr  error
+	Lazy       bool
+	BigEndian  bool
+	Offsets    map[string]int64
 	Meta       map[string]*Meta
 	ParentBase interface{}
 	RootBase   interface{}
@@ -30,19 +61,108 @@ func NewTypeIO(reader io.ReadSeeker, instance interface{}, ancestors ...interfac
 	if len(ancestors) == 2 {
 		ret.ParentBase = ancestors[0]
 		ret.RootBase = ancestors[1]
+	} else if len(ancestors) == 1 {
+		// root type of an imported spec
+		ret.ParentBase = ancestors[0]
+		ret.RootBase = instance
 	} else if len(ancestors) == 0 {
 		ret.ParentBase = instance
 		ret.RootBase = instance
 	} else {
 		ret.DecodeErr = errors.New("to many ancestors are given")
 	}
+	// types inherit a calculated endianness from their parent
+	if parent, ok := ret.ParentBase.(interface{ IsBigEndian() bool }); ok && ret.ParentBase != instance {
+		ret.BigEndian = parent.IsBigEndian()
+	}
+	return
+}
+
+// Err returns the first error that occurred while decoding. This includes
+// the errors of deferred fields and instances, which are read by their
+// getters. A getter, that fails, returns the zero or partially read value
+// and the error is kept, so Err should be checked after using the getters.
+func (k *TypeIO) Err() error {
+	return k.DecodeErr
+}
+
+// SetErr records the error of a getter, unless an earlier error is recorded.
+func (k *TypeIO) SetErr(err error) {
+	if k.DecodeErr == nil {
+		k.DecodeErr = err
+	}
+}
+
+// IsBigEndian returns true if the calculated endianness of the type is big
+// endian. Types that were not decoded are little endian.
+func (k *TypeIO) IsBigEndian() bool {
+	return k != nil && k.BigEndian
+}
+
+// Defer records the current offset of the field name and skips n bytes of
+// the stream instead of parsing the field. If eos is set, n is ignored and
+// the rest of the stream is skipped. Skipping beyond the end of the stream
+// returns an error like reading the field would.
+func (k *TypeIO) Defer(name string, n int64, eos bool) (err error) {
+	var pos, size int64
+	if pos, err = k.Pos(); err != nil {
+		return
+	}
+	if size, err = k.Size(); err != nil {
+		return
+	}
+	if eos {
+		n = size - pos
+	} else if n < 0 {
+		return fmt.Errorf("Defer(%q, %d): negative number of bytes to skip", name, n)
+	} else if n > size-pos {
+		return fmt.Errorf("Defer(%q, %d): only %d bytes left at position %d: %w", name, n, size-pos, pos, io.ErrUnexpectedEOF)
+	}
+	if k.Offsets == nil {
+		k.Offsets = map[string]int64{}
+	}
+	k.Offsets[name] = pos
+	_, err = k.Seek(n, io.SeekCurrent)
 	return
 }
 
-func (k *TypeIO) ReadBytesAsReader(n uint16) (ret io.ReadSeeker, err error) {
-	var raw []byte
-	if raw, err = k.ReadBytes(n); err == nil {
-		ret = bytes.NewReader(raw)
+// Deferred returns true when the field name was skipped by Defer and is not
+// materialized yet. Types that were not decoded have no deferred fields.
+func (k *TypeIO) Deferred(name string) bool {
+	if k == nil {
+		return false
+	}
+	_, ok := k.Offsets[name]
+	return ok
+}
+
+// Undefer forgets the offset of the field name, e.g. because a new value was
+// set.
+func (k *TypeIO) Undefer(name string) {
+	if k != nil {
+		delete(k.Offsets, name)
+	}
+}
+
+// Materialize seeks to the recorded offset of the field name, parses it with
+// read and restores the stream position afterwards.
+func (k *TypeIO) Materialize(name string, read func() error) (err error) {
+	offset, ok := k.Offsets[name]
+	if !ok {
+		return
+	}
+	var pos int64
+	if pos, err = k.Pos(); err != nil {
+		return
+	}
+	if _, err = k.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+	if err = read(); err == nil {
+		delete(k.Offsets, name)
+	}
+	if _, seekErr := k.Seek(pos, io.SeekStart); err == nil {
+		err = seekErr
 	}
 	return
 }
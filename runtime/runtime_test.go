@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefer(t *testing.T) {
+	k := &TypeIO{Stream: NewStream(bytes.NewReader([]byte{1, 2, 3, 4, 5}))}
+	assert.NoError(t, k.Defer("a", 2, false))
+	assert.NoError(t, k.Defer("b", 0, true))
+	assert.EqualValues(t, map[string]int64{"a": 0, "b": 2}, k.Offsets)
+	pos, _ := k.Pos()
+	assert.EqualValues(t, 5, pos)
+
+	var b []byte
+	assert.NoError(t, k.Materialize("b", func() (err error) {
+		b, err = k.ReadBytesFull()
+		return
+	}))
+	assert.Equal(t, []byte{3, 4, 5}, b)
+	assert.False(t, k.Deferred("b"))
+	assert.True(t, k.Deferred("a"))
+	pos, _ = k.Pos()
+	assert.EqualValues(t, 5, pos)
+
+	k = &TypeIO{Stream: NewStream(bytes.NewReader([]byte{1, 2, 3}))}
+	err := k.Defer("c", 4, false)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+	assert.Error(t, k.Defer("c", -1, false))
+	assert.False(t, k.Deferred("c"))
+	pos, _ = k.Pos()
+	assert.EqualValues(t, 0, pos)
+}
+
+func TestSetErr(t *testing.T) {
+	k := &TypeIO{}
+	k.SetErr(nil)
+	assert.NoError(t, k.Err())
+	k.SetErr(io.EOF)
+	k.SetErr(io.ErrUnexpectedEOF)
+	k.SetErr(nil)
+	assert.Equal(t, io.EOF, k.Err())
+}
@@ -30,6 +30,13 @@ func NewStream(r io.ReadSeeker) *Stream {
 	return &Stream{ReadSeeker: r}
 }
 
+// Read reads up to len(p) bytes into p. Reading bytes aligns the stream, the
+// remaining bits of a previous bit read are discarded.
+func (k *Stream) Read(p []byte) (n int, err error) {
+	k.AlignToByte()
+	return k.ReadSeeker.Read(p)
+}
+
 // EOF returns true when the end of the Stream is reached.
 func (k *Stream) EOF() (bool, error) {
 	if k.bitsLeft > 0 {
@@ -85,7 +92,7 @@ func (k *Stream) Pos() (int64, error) {
 
 // ReadU1 reads 1 byte and returns this as uint8.
 func (k *Stream) ReadU1() (v uint8, err error) {
-	if _, err = k.Read(k.buf[:1]); err != nil {
+	if _, err = io.ReadFull(k, k.buf[:1]); err != nil {
 		return 0, err
 	}
 	return k.buf[0], nil
@@ -98,7 +105,7 @@ func (k *Stream) ReadU1le() (v uint8, err error) {
 
 // ReadU2be reads 2 bytes in big-endian order and returns those as uint16.
 func (k *Stream) ReadU2be() (v uint16, err error) {
-	if _, err = k.Read(k.buf[:2]); err != nil {
+	if _, err = io.ReadFull(k, k.buf[:2]); err != nil {
 		return 0, err
 	}
 	return binary.BigEndian.Uint16(k.buf[:2]), nil
@@ -106,7 +113,7 @@ func (k *Stream) ReadU2be() (v uint16, err error) {
 
 // ReadU4be reads 4 bytes in big-endian order and returns those as uint32.
 func (k *Stream) ReadU4be() (v uint32, err error) {
-	if _, err = k.Read(k.buf[:4]); err != nil {
+	if _, err = io.ReadFull(k, k.buf[:4]); err != nil {
 		return 0, err
 	}
 	return binary.BigEndian.Uint32(k.buf[:4]), nil
@@ -114,7 +121,7 @@ func (k *Stream) ReadU4be() (v uint32, err error) {
 
 // ReadU8be reads 8 bytes in big-endian order and returns those as uint64.
 func (k *Stream) ReadU8be() (v uint64, err error) {
-	if _, err = k.Read(k.buf[:8]); err != nil {
+	if _, err = io.ReadFull(k, k.buf[:8]); err != nil {
 		return 0, err
 	}
 	return binary.BigEndian.Uint64(k.buf[:8]), nil
@@ -122,7 +129,7 @@ func (k *Stream) ReadU8be() (v uint64, err error) {
 
 // ReadU2le reads 2 bytes in little-endian order and returns those as uint16.
 func (k *Stream) ReadU2le() (v uint16, err error) {
-	if _, err = k.Read(k.buf[:2]); err != nil {
+	if _, err = io.ReadFull(k, k.buf[:2]); err != nil {
 		return 0, err
 	}
 	return binary.LittleEndian.Uint16(k.buf[:2]), nil
@@ -130,7 +137,7 @@ func (k *Stream) ReadU2le() (v uint16, err error) {
 
 // ReadU4le reads 4 bytes in little-endian order and returns those as uint32.
 func (k *Stream) ReadU4le() (v uint32, err error) {
-	if _, err = k.Read(k.buf[:4]); err != nil {
+	if _, err = io.ReadFull(k, k.buf[:4]); err != nil {
 		return 0, err
 	}
 	return binary.LittleEndian.Uint32(k.buf[:4]), nil
@@ -138,7 +145,7 @@ func (k *Stream) ReadU4le() (v uint32, err error) {
 
 // ReadU8le reads 8 bytes in little-endian order and returns those as uint64.
 func (k *Stream) ReadU8le() (v uint64, err error) {
-	if _, err = k.Read(k.buf[:8]); err != nil {
+	if _, err = io.ReadFull(k, k.buf[:8]); err != nil {
 		return 0, err
 	}
 	return binary.LittleEndian.Uint64(k.buf[:8]), nil
@@ -210,11 +217,46 @@ func (k *Stream) ReadF8le() (v float64, err error) {
 	return math.Float64frombits(vv), err
 }
 
-// ReadBytes reads n bytes and returns those as a byte array.
-func (k *Stream) ReadBytes(n uint16) (b []byte, err error) {
+// CheckSize returns an error, if the unsigned size n of a field does not fit
+// into the int64 sizes of ReadBytes.
+func CheckSize(n uint64) error {
+	if n > math.MaxInt64 {
+		return fmt.Errorf("size %d overflows int64", n)
+	}
+	return nil
+}
+
+// trustedSize is the number of bytes ReadBytes allocates without checking
+// the size of the stream.
+const trustedSize = 1 << 16
+
+// ReadBytes reads n bytes and returns those as a byte array. Negative sizes
+// and sizes, that overflow int, return an error. Sizes above trustedSize are
+// checked against the remaining bytes of the stream before the buffer is
+// allocated.
+func (k *Stream) ReadBytes(n int64) (b []byte, err error) {
 	if n < 0 {
 		return nil, fmt.Errorf("ReadBytes(%d): negative number of bytes to read", n)
 	}
+	if int64(int(n)) != n {
+		return nil, fmt.Errorf("ReadBytes(%d): number of bytes to read overflows int", n)
+	}
+	if n > trustedSize {
+		// large sizes are often garbage from broken files
+		var pos, size int64
+		if pos, err = k.Pos(); err != nil {
+			return nil, err
+		}
+		if size, err = k.Size(); err != nil {
+			return nil, err
+		}
+		if size == pos {
+			return nil, io.EOF
+		}
+		if n > size-pos {
+			return nil, fmt.Errorf("ReadBytes(%d): only %d bytes left at position %d: %w", n, size-pos, pos, io.ErrUnexpectedEOF)
+		}
+	}
 
 	b = make([]byte, n)
 	_, err = io.ReadFull(k, b)
@@ -222,7 +264,7 @@ func (k *Stream) ReadBytes(n uint16) (b []byte, err error) {
 }
 
 // ReadBytesString reads n bytes and returns those as a byte array.
-func (k *Stream) ReadBytesString(n uint16) (ret string, err error) {
+func (k *Stream) ReadBytesString(n int64) (ret string, err error) {
 	var data []byte
 	if data, err = k.ReadBytes(n); err == nil {
 		ret = string(data)
@@ -230,11 +272,31 @@ func (k *Stream) ReadBytesString(n uint16) (ret string, err error) {
 	return
 }
 
+// ReadBytesAsReader reads n bytes and returns a reader for them, e.g. to
+// parse a sized type.
+func (k *Stream) ReadBytesAsReader(n int64) (ret io.ReadSeeker, err error) {
+	var raw []byte
+	if raw, err = k.ReadBytes(n); err == nil {
+		ret = bytes.NewReader(raw)
+	}
+	return
+}
+
 // ReadBytesFull reads all remaining bytes and returns those as a byte array.
 func (k *Stream) ReadBytesFull() ([]byte, error) {
 	return ioutil.ReadAll(k)
 }
 
+// ReadBytesFullAsReader reads all remaining bytes and returns a reader for
+// them, e.g. to parse a type with size-eos.
+func (k *Stream) ReadBytesFullAsReader() (ret io.ReadSeeker, err error) {
+	var raw []byte
+	if raw, err = k.ReadBytesFull(); err == nil {
+		ret = bytes.NewReader(raw)
+	}
+	return
+}
+
 // ReadBytesFullString reads all remaining bytes and returns those as a byte array.
 func (k *Stream) ReadBytesFullString() (ret string, err error) {
 	var data []byte
@@ -247,24 +309,34 @@ func (k *Stream) ReadBytesFullString() (ret string, err error) {
 // ReadBytesPadTerm reads up to size bytes. pad bytes are discarded. It
 // terminates reading, when the term byte occurs. The term byte is included
 // in the returned byte array when includeTerm is set.
-func (k *Stream) ReadBytesPadTerm(size uint16, term, pad byte, includeTerm bool) ([]byte, error) {
+func (k *Stream) ReadBytesPadTerm(size int64, term, pad byte, includeTerm bool) ([]byte, error) {
 	bs, err := k.ReadBytes(size)
 	if err != nil {
 		return nil, err
 	}
+	return BytesTerminate(BytesStripRight(bs, pad), term, includeTerm), nil
+}
 
-	bs = bytes.TrimRight(bs, string(pad))
+// BytesStripRight removes all trailing pad bytes.
+func BytesStripRight(bs []byte, pad byte) []byte {
+	i := len(bs)
+	for i > 0 && bs[i-1] == pad {
+		i--
+	}
+	return bs[:i]
+}
 
+// BytesTerminate cuts bs at the first term byte. The term byte is included
+// in the returned byte array when includeTerm is set.
+func BytesTerminate(bs []byte, term byte, includeTerm bool) []byte {
 	i := bytes.IndexByte(bs, term)
-	if i != -1 {
-		if includeTerm {
-			bs = bs[:i+1]
-		} else {
-			bs = bs[:i]
-		}
+	if i == -1 {
+		return bs
 	}
-
-	return bs, nil
+	if includeTerm {
+		return bs[:i+1]
+	}
+	return bs[:i]
 }
 
 // ReadBytesTerm reads bytes until the term byte is reached. If includeTerm is
@@ -295,62 +367,65 @@ func (k *Stream) ReadBytesTerm(term byte, includeTerm, consumeTerm, eosError boo
 	return slice, err
 }
 
-// ReadStrEOS reads the remaining bytes as a string.
+// ReadStrEOS reads the remaining bytes as a string in the given encoding.
 func (k *Stream) ReadStrEOS(encoding string) (string, error) {
 	buf, err := ioutil.ReadAll(k)
-
-	// Go's string type can contain any bytes.  The Go `range` operator
-	// assumes that the encoding is UTF-8 and some standard Go libraries
-	// also would like UTF-8.  For now we'll leave any advanced
-	// conversions up to the user.
-	return string(buf), err
+	if err != nil {
+		return "", err
+	}
+	return DecodeString(buf, encoding)
 }
 
-// ReadStrByteLimit reads limit number of bytes and returns those as a string.
+// ReadStrByteLimit reads limit number of bytes and returns those as a string
+// in the given encoding.
 func (k *Stream) ReadStrByteLimit(limit int, encoding string) (string, error) {
 	buf := make([]byte, limit)
-	n, err := k.Read(buf)
-	return string(buf[:n]), err
+	n, err := io.ReadFull(k, buf)
+	if err != nil {
+		return string(buf[:n]), err
+	}
+	return DecodeString(buf, encoding)
 }
 
 // AlignToByte discards the remaining bits and starts reading bits at the
 // next byte.
 func (k *Stream) AlignToByte() {
 	k.bitsLeft = 0
+	k.bits = 0
 }
 
 // ReadBitsIntBe reads n-bit integer in big-endian byte order and returns it as uint64.
 func (k *Stream) ReadBitsIntBe(n uint8) (res uint64, err error) {
-	bitsNeeded := int(n) - int(k.bitsLeft)
-	if bitsNeeded > 0 {
-		// 1 bit  => 1 byte
-		// 8 bits => 1 byte
-		// 9 bits => 2 bytes
-		bytesNeeded := ((bitsNeeded - 1) / 8) + 1
-		if bytesNeeded > 8 {
-			return res, fmt.Errorf("ReadBitsIntBe(%d): more than 8 bytes requested", n)
-		}
-		_, err = k.Read(k.buf[:bytesNeeded])
-		if err != nil {
-			return res, err
-		}
-		for i := 0; i < bytesNeeded; i++ {
-			k.bits <<= 8
-			k.bits |= uint64(k.buf[i])
-			k.bitsLeft += 8
-		}
+	if n > 64 {
+		return 0, fmt.Errorf("ReadBitsIntBe(%d): more than 64 bits requested", n)
+	}
+	if n <= k.bitsLeft {
+		k.bitsLeft -= n
+		res = k.bits >> k.bitsLeft & bitsMask(n)
+		k.bits &= bitsMask(k.bitsLeft)
+		return res, nil
+	}
+
+	// 1 bit  => 1 byte
+	// 8 bits => 1 byte
+	// 9 bits => 2 bytes
+	bitsNeeded := n - k.bitsLeft
+	bytesNeeded := (bitsNeeded + 7) / 8
+	if _, err = io.ReadFull(k.ReadSeeker, k.buf[:bytesNeeded]); err != nil {
+		return 0, err
 	}
-
-	// raw mask with required number of 1s, starting from lowest bit
-	var mask uint64 = (1 << n) - 1
-	// shift "bits" to align the highest bits with the mask & derive the result
-	shiftBits := k.bitsLeft - n
-	res = (k.bits >> shiftBits) & mask
-	// clear top bits that we've just read => AND with 1s
-	k.bitsLeft -= n
-	mask = (1 << k.bitsLeft) - 1
-	k.bits &= mask
-	return res, err
+	// the remaining bits are the highest bits of the result
+	res = k.bits
+	for _, b := range k.buf[:bytesNeeded-1] {
+		res = res<<8 | uint64(b)
+	}
+	// take the highest bits of the last byte and keep the others
+	last := uint64(k.buf[bytesNeeded-1])
+	used := bitsNeeded - 8*(bytesNeeded-1)
+	res = res<<used | last>>(8-used)
+	k.bitsLeft = 8 - used
+	k.bits = last & bitsMask(k.bitsLeft)
+	return res, nil
 }
 
 func (k *Stream) ReadB1le() (res uint, err error) {
@@ -430,37 +505,53 @@ func (k *Stream) ReadBitsInt(n uint8) (res uint64, err error) {
 
 // ReadBitsIntLe reads n-bit integer in little-endian byte order and returns it as uint64.
 func (k *Stream) ReadBitsIntLe(n uint8) (res uint64, err error) {
-	bitsNeeded := int(n) - int(k.bitsLeft)
-	var bitsLeft uint64 = uint64(k.bitsLeft)
-	if bitsNeeded > 0 {
-		// 1 bit  => 1 byte
-		// 8 bits => 1 byte
-		// 9 bits => 2 bytes
-		bytesNeeded := ((bitsNeeded - 1) / 8) + 1
-		if bytesNeeded > 8 {
-			return res, fmt.Errorf("ReadBitsIntLe(%d): more than 8 bytes requested", n)
-		}
-		_, err = k.Read(k.buf[:bytesNeeded])
-		if err != nil {
-			return res, err
-		}
-		for i := 0; i < bytesNeeded; i++ {
-			k.bits |= uint64(k.buf[i]) << bitsLeft
-			bitsLeft += 8
-		}
+	if n > 64 {
+		return 0, fmt.Errorf("ReadBitsIntLe(%d): more than 64 bits requested", n)
+	}
+	if n <= k.bitsLeft {
+		res = k.bits & bitsMask(n)
+		k.bits >>= n
+		k.bitsLeft -= n
+		return res, nil
 	}
 
-	// raw mask with required number of 1s, starting from lowest bit
-	var mask uint64 = (1 << n) - 1
-	// derive reading result
-	res = k.bits & mask
-	// remove bottom bits that we've just read by shifting
-	k.bits >>= n
-	k.bitsLeft = uint8(bitsLeft) - n
-	return res, err
-}
-
-// ReadBitsArray is not implemented yet.
-func (k *Stream) ReadBitsArray(n uint) error {
-	return nil // TODO: implement
+	bitsNeeded := n - k.bitsLeft
+	bytesNeeded := (bitsNeeded + 7) / 8
+	if _, err = io.ReadFull(k.ReadSeeker, k.buf[:bytesNeeded]); err != nil {
+		return 0, err
+	}
+	// the remaining bits are the lowest bits of the result
+	res = k.bits
+	shift := k.bitsLeft
+	for _, b := range k.buf[:bytesNeeded-1] {
+		res |= uint64(b) << shift
+		shift += 8
+	}
+	// take the lowest bits of the last byte and keep the others
+	last := uint64(k.buf[bytesNeeded-1])
+	used := n - shift
+	res |= (last & bitsMask(used)) << shift
+	k.bitsLeft = 8 - used
+	k.bits = last >> used
+	return res, nil
+}
+
+// bitsMask returns a mask of the n lowest bits.
+func bitsMask(n uint8) uint64 {
+	if n >= 64 {
+		return math.MaxUint64
+	}
+	return 1<<n - 1
+}
+
+// ReadBitsArray reads n bits in big-endian bit order and returns those as
+// flags, the first bit read comes first.
+func (k *Stream) ReadBitsArray(n uint) (bits []bool, err error) {
+	bits = make([]bool, n)
+	for i := range bits {
+		if bits[i], err = k.ReadB1beBool(); err != nil {
+			return nil, err
+		}
+	}
+	return bits, nil
 }
@@ -0,0 +1,57 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBytes(t *testing.T) {
+	k := NewStream(bytes.NewReader([]byte{1, 2, 3}))
+	b, err := k.ReadBytes(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2}, b)
+
+	_, err = k.ReadBytes(-1)
+	assert.EqualError(t, err, "ReadBytes(-1): negative number of bytes to read")
+
+	// small sizes are read like before
+	_, err = k.ReadBytes(2)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+
+	// large sizes are checked before allocating
+	k = NewStream(bytes.NewReader([]byte{1, 2, 3}))
+	_, err = k.ReadBytes(math.MaxInt64)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+	assert.Contains(t, err.Error(), "only 3 bytes left at position 0")
+	pos, _ := k.Pos()
+	assert.EqualValues(t, 0, pos)
+
+	_, err = k.ReadBytes(trustedSize + 1)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+
+	_, _ = k.ReadBytes(3)
+	_, err = k.ReadBytes(trustedSize + 1)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCheckSize(t *testing.T) {
+	assert.NoError(t, CheckSize(0))
+	assert.NoError(t, CheckSize(math.MaxInt64))
+	assert.EqualError(t, CheckSize(math.MaxInt64+1), "size 9223372036854775808 overflows int64")
+	assert.EqualError(t, CheckSize(math.MaxUint64), "size 18446744073709551615 overflows int64")
+}
+
+func TestWriteAfterBits(t *testing.T) {
+	var buf Buffer
+	k := NewWriter(&buf)
+	assert.NoError(t, k.WriteBitsIntBe(3, 5))
+	assert.NoError(t, k.WriteU1(0x42))
+	assert.NoError(t, k.WriteBitsIntLe(3, 5))
+	assert.NoError(t, k.WriteU2be(0x1234))
+	assert.Equal(t, []byte{0xa0, 0x42, 0x05, 0x12, 0x34}, buf.Bytes())
+}
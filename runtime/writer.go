@@ -0,0 +1,352 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Encoder is implemented by all generated types and writes the seq of the
+// type to a Writer.
+type Encoder interface {
+	Write(w *Writer) error
+}
+
+// A Writer is the counterpart of Stream. It writes primitives to an
+// io.WriteSeeker.
+type Writer struct {
+	io.WriteSeeker
+	buf [8]byte
+
+	// Number of pending bits in "bits" of sequential calls to WriteBitsInt
+	bitsLeft uint8
+	bits     uint64
+	bitsLe   bool
+}
+
+// NewWriter creates and initializes a new Writer based on w.
+func NewWriter(w io.WriteSeeker) *Writer {
+	return &Writer{WriteSeeker: w}
+}
+
+// Pos returns the current position of the writer.
+func (k *Writer) Pos() (int64, error) {
+	return k.Seek(0, io.SeekCurrent)
+}
+
+// AlignToByte writes the pending bits, filled up with zero bits to a whole
+// byte.
+func (k *Writer) AlignToByte() (err error) {
+	if k.bitsLeft == 0 {
+		return
+	}
+	// not buffered in buf, which may hold the value of the next write
+	last := []byte{byte(k.bits << (8 - k.bitsLeft))}
+	if k.bitsLe {
+		last[0] = byte(k.bits)
+	}
+	k.bitsLeft = 0
+	k.bits = 0
+	_, err = k.WriteSeeker.Write(last)
+	return
+}
+
+func (k *Writer) write(b []byte) (err error) {
+	if err = k.AlignToByte(); err != nil {
+		return
+	}
+	_, err = k.WriteSeeker.Write(b)
+	return
+}
+
+// WriteU1 writes v as 1 byte.
+func (k *Writer) WriteU1(v uint8) error {
+	k.buf[0] = v
+	return k.write(k.buf[:1])
+}
+
+// WriteU1le writes v as 1 byte.
+func (k *Writer) WriteU1le(v uint8) error {
+	return k.WriteU1(v)
+}
+
+// WriteU2be writes v as 2 bytes in big-endian order.
+func (k *Writer) WriteU2be(v uint16) error {
+	binary.BigEndian.PutUint16(k.buf[:2], v)
+	return k.write(k.buf[:2])
+}
+
+// WriteU4be writes v as 4 bytes in big-endian order.
+func (k *Writer) WriteU4be(v uint32) error {
+	binary.BigEndian.PutUint32(k.buf[:4], v)
+	return k.write(k.buf[:4])
+}
+
+// WriteU8be writes v as 8 bytes in big-endian order.
+func (k *Writer) WriteU8be(v uint64) error {
+	binary.BigEndian.PutUint64(k.buf[:8], v)
+	return k.write(k.buf[:8])
+}
+
+// WriteU2le writes v as 2 bytes in little-endian order.
+func (k *Writer) WriteU2le(v uint16) error {
+	binary.LittleEndian.PutUint16(k.buf[:2], v)
+	return k.write(k.buf[:2])
+}
+
+// WriteU4le writes v as 4 bytes in little-endian order.
+func (k *Writer) WriteU4le(v uint32) error {
+	binary.LittleEndian.PutUint32(k.buf[:4], v)
+	return k.write(k.buf[:4])
+}
+
+// WriteU8le writes v as 8 bytes in little-endian order.
+func (k *Writer) WriteU8le(v uint64) error {
+	binary.LittleEndian.PutUint64(k.buf[:8], v)
+	return k.write(k.buf[:8])
+}
+
+// WriteS1 writes v as 1 byte.
+func (k *Writer) WriteS1(v int8) error {
+	return k.WriteU1(uint8(v))
+}
+
+// WriteS1le writes v as 1 byte.
+func (k *Writer) WriteS1le(v int8) error {
+	return k.WriteU1(uint8(v))
+}
+
+// WriteS2be writes v as 2 bytes in big-endian order.
+func (k *Writer) WriteS2be(v int16) error {
+	return k.WriteU2be(uint16(v))
+}
+
+// WriteS4be writes v as 4 bytes in big-endian order.
+func (k *Writer) WriteS4be(v int32) error {
+	return k.WriteU4be(uint32(v))
+}
+
+// WriteS8be writes v as 8 bytes in big-endian order.
+func (k *Writer) WriteS8be(v int64) error {
+	return k.WriteU8be(uint64(v))
+}
+
+// WriteS2le writes v as 2 bytes in little-endian order.
+func (k *Writer) WriteS2le(v int16) error {
+	return k.WriteU2le(uint16(v))
+}
+
+// WriteS4le writes v as 4 bytes in little-endian order.
+func (k *Writer) WriteS4le(v int32) error {
+	return k.WriteU4le(uint32(v))
+}
+
+// WriteS8le writes v as 8 bytes in little-endian order.
+func (k *Writer) WriteS8le(v int64) error {
+	return k.WriteU8le(uint64(v))
+}
+
+// WriteF4be writes v as 4 bytes in big-endian order.
+func (k *Writer) WriteF4be(v float32) error {
+	return k.WriteU4be(math.Float32bits(v))
+}
+
+// WriteF8be writes v as 8 bytes in big-endian order.
+func (k *Writer) WriteF8be(v float64) error {
+	return k.WriteU8be(math.Float64bits(v))
+}
+
+// WriteF4le writes v as 4 bytes in little-endian order.
+func (k *Writer) WriteF4le(v float32) error {
+	return k.WriteU4le(math.Float32bits(v))
+}
+
+// WriteF8le writes v as 8 bytes in little-endian order.
+func (k *Writer) WriteF8le(v float64) error {
+	return k.WriteU8le(math.Float64bits(v))
+}
+
+// WriteBytes writes b.
+func (k *Writer) WriteBytes(b []byte) error {
+	return k.write(b)
+}
+
+// WriteBytesTerm writes b followed by the term byte. The term byte is not
+// written again, if includeTerm is set, as it is part of b then. It is
+// not written either, if consumeTerm is not set, as it belongs to the next
+// field then.
+func (k *Writer) WriteBytesTerm(b []byte, term byte, includeTerm, consumeTerm bool) error {
+	if err := k.write(b); err != nil {
+		return err
+	}
+	if includeTerm || !consumeTerm {
+		return nil
+	}
+	k.buf[0] = term
+	return k.write(k.buf[:1])
+}
+
+// WriteBytesLimit writes b into a field of size bytes. If b is shorter, the
+// term byte is written once and the remaining bytes are filled with pad.
+func (k *Writer) WriteBytesLimit(b []byte, size int64, term, pad byte) error {
+	if int64(len(b)) > size {
+		return fmt.Errorf("WriteBytesLimit(%d): %d bytes do not fit", size, len(b))
+	}
+	if err := k.write(b); err != nil {
+		return err
+	}
+	if int64(len(b)) == size {
+		return nil
+	}
+	rest := make([]byte, size-int64(len(b)))
+	rest[0] = term
+	for i := 1; i < len(rest); i++ {
+		rest[i] = pad
+	}
+	return k.write(rest)
+}
+
+// PadTo fills the field, that started at start, with zero bytes up to size
+// bytes.
+func (k *Writer) PadTo(start, size int64) error {
+	if err := k.AlignToByte(); err != nil {
+		return err
+	}
+	pos, err := k.Pos()
+	if err != nil {
+		return err
+	}
+	if pos-start > size {
+		return fmt.Errorf("PadTo(%d): %d bytes written", size, pos-start)
+	}
+	return k.write(make([]byte, size-(pos-start)))
+}
+
+// WriteBitsIntBe writes the n lowest bits of v in big-endian byte order.
+func (k *Writer) WriteBitsIntBe(n uint8, v uint64) (err error) {
+	if n > 64 {
+		return fmt.Errorf("WriteBitsIntBe(%d): more than 64 bits", n)
+	}
+	if k.bitsLeft > 0 && k.bitsLe {
+		if err = k.AlignToByte(); err != nil {
+			return
+		}
+	}
+	if n > 32 {
+		// keep room for the pending bits in the uint64
+		if err = k.WriteBitsIntBe(n-32, v>>32); err != nil {
+			return
+		}
+		n = 32
+	}
+	mask := uint64(1)<<n - 1
+	k.bits = k.bits<<n | v&mask
+	k.bitsLeft += n
+	k.bitsLe = false
+	for k.bitsLeft >= 8 {
+		k.bitsLeft -= 8
+		k.buf[0] = byte(k.bits >> k.bitsLeft)
+		k.bits &= uint64(1)<<k.bitsLeft - 1
+		if _, err = k.WriteSeeker.Write(k.buf[:1]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// WriteBitsIntLe writes the n lowest bits of v in little-endian byte order.
+func (k *Writer) WriteBitsIntLe(n uint8, v uint64) (err error) {
+	if n > 64 {
+		return fmt.Errorf("WriteBitsIntLe(%d): more than 64 bits", n)
+	}
+	if k.bitsLeft > 0 && !k.bitsLe {
+		if err = k.AlignToByte(); err != nil {
+			return
+		}
+	}
+	if n > 32 {
+		// keep room for the pending bits in the uint64
+		if err = k.WriteBitsIntLe(32, v); err != nil {
+			return
+		}
+		n -= 32
+		v >>= 32
+	}
+	mask := uint64(1)<<n - 1
+	k.bits |= (v & mask) << k.bitsLeft
+	k.bitsLeft += n
+	k.bitsLe = true
+	for k.bitsLeft >= 8 {
+		k.buf[0] = byte(k.bits)
+		k.bits >>= 8
+		k.bitsLeft -= 8
+		if _, err = k.WriteSeeker.Write(k.buf[:1]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// WriteB1beBool writes v as a single bit in big-endian byte order.
+func (k *Writer) WriteB1beBool(v bool) error {
+	return k.WriteBitsIntBe(1, boolBit(v))
+}
+
+// WriteB1leBool writes v as a single bit in little-endian byte order.
+func (k *Writer) WriteB1leBool(v bool) error {
+	return k.WriteBitsIntLe(1, boolBit(v))
+}
+
+// Buffer is an in-memory io.WriteSeeker. The zero value is an empty buffer
+// ready to use.
+type Buffer struct {
+	data []byte
+	pos  int64
+}
+
+// Write writes p at the current position and grows the buffer if needed.
+func (b *Buffer) Write(p []byte) (int, error) {
+	end := b.pos + int64(len(p))
+	if end > int64(len(b.data)) {
+		if end > int64(cap(b.data)) {
+			data := make([]byte, end, 2*end)
+			copy(data, b.data)
+			b.data = data
+		}
+		b.data = b.data[:end]
+	}
+	copy(b.data[b.pos:], p)
+	b.pos = end
+	return len(p), nil
+}
+
+// Seek sets the position for the next Write.
+func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.pos
+	case io.SeekEnd:
+		offset += int64(len(b.data))
+	default:
+		return 0, fmt.Errorf("Seek: invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("Seek: negative position %d", offset)
+	}
+	b.pos = offset
+	return offset, nil
+}
+
+// Bytes returns the written data.
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+func boolBit(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
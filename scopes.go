@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// typeScopes maps the Go name of every user type of the spec to the type.
+// Identifiers in expressions are resolved against the seq, instances and
+// params of the type they are used in.
+var typeScopes map[string]*Type
+
+func addTypeScope(k *Type) {
+	if _, ok := typeScopes[k.name]; !ok {
+		typeScopes[k.name] = k
+	}
+}
+
+// getTypeScope returns the user type of a Go type like *Foo, or nil if the
+// type is not a user type of the spec, e.g. an imported type.
+func getTypeScope(goType string) *Type {
+	return typeScopes[strings.TrimPrefix(goType, "*")]
+}
+
+// cycleError is returned for value instances, whose type depends on itself.
+type cycleError struct {
+	name, typeName string
+}
+
+func (e *cycleError) Error() string {
+	return fmt.Sprintf("type of %q in type %s depends on itself", e.name, e.typeName)
+}
+
+// resolving holds the value instances whose type is inferred right now, to
+// stop on instances that depend on themselves.
+var resolving = map[string]bool{}
+
+// fieldType returns the Go type of the attribute, instance or param name of
+// k.
+func (k *Type) fieldType(name string) (string, error) {
+	for _, param := range k.Params {
+		if param.ID == name {
+			return param.ParamType(), nil
+		}
+	}
+	attr, ok := k.Instances[name]
+	if !ok {
+		found := false
+		for _, a := range k.Seq {
+			if a.ID == name {
+				attr, found = a, true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("unknown attribute %q in type %s", name, k.name)
+		}
+	}
+	key := k.name + "." + name
+	if resolving[key] {
+		return "", &cycleError{name, k.name}
+	}
+	resolving[key] = true
+	defer delete(resolving, key)
+	if attr.Value != "" && attr.Enum == "" && attr.Type.String() == "[]byte" {
+		// other errors of the value are reported at the instance itself
+		n, err := parseExpr(attr.Value)
+		if err != nil {
+			return "", nil
+		}
+		c := newExprContext(k.name, "", "")
+		t := c.typeOf(n)
+		if err, ok := c.err.(*cycleError); ok {
+			return "", err
+		}
+		return t, nil
+	}
+	switch t := attr.DataType(); t {
+	case "interface{}", "runtime.Decoder":
+		// any or switched type
+		return "", nil
+	default:
+		return t, nil
+	}
+}
+
+// parentType returns the Go type of _parent in k or an empty string, if the
+// parent is not known.
+func (k *Type) parentType() string {
+	if parent := getParent(k.name); parent != "" {
+		return "*" + parent
+	}
+	return ""
+}
@@ -29,3 +29,21 @@ func TestBcdUserTypeLe(t *testing.T) {
 	assert.EqualValues(t, 123456, r.LeadingZeroLtr().AsInt())
 	assert.EqualValues(t, "00123456", r.LeadingZeroLtr().AsStr())
 }
+
+func TestBcdUserTypeLeEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/bcd_user_type_le.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r BcdUserTypeLe
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -0,0 +1,31 @@
+package bits_byte_aligned
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitsByteAligned(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r BitsByteAligned
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x14, r.One())
+	assert.EqualValues(t, 0x41, r.Byte1())
+	assert.EqualValues(t, 2, r.Two())
+	assert.False(t, r.Three())
+	assert.EqualValues(t, 0x4b, r.Byte2())
+	assert.EqualValues(t, 0xb4c, r.Four())
+	assert.Equal(t, []byte{0xff}, r.Byte3())
+	assert.EqualValues(t, 0xff, r.FullByte())
+	assert.EqualValues(t, 0x50, r.Byte4())
+}
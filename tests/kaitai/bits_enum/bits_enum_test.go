@@ -0,0 +1,25 @@
+package bits_enum
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitsEnum(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r BitsEnum
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, AnimalPlatypus, r.One())
+	assert.Equal(t, AnimalHorse, r.Two())
+	assert.Equal(t, AnimalCat, r.Three())
+}
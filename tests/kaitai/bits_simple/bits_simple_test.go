@@ -0,0 +1,58 @@
+package bits_simple
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitsSimple(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r BitsSimple
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x50, r.Byte1())
+	assert.EqualValues(t, 0x41, r.Byte2())
+	assert.False(t, r.BitsA())
+	assert.EqualValues(t, 4, r.BitsB())
+	assert.EqualValues(t, 3, r.BitsC())
+	assert.EqualValues(t, 0x12c, r.LargeBits1())
+	assert.EqualValues(t, 5, r.Spacer())
+	assert.EqualValues(t, 0x531, r.LargeBits2())
+	assert.EqualValues(t, -1, r.NormalS2())
+	assert.EqualValues(t, 0x504143, r.Byte8910())
+	assert.EqualValues(t, 0x4b2d552d, r.Byte11To14())
+	assert.EqualValues(t, 0x444546ffff, r.Byte15To19())
+	assert.EqualValues(t, uint64(0xffffffffffffffff), r.Byte20To27())
+	assert.EqualValues(t, 123, r.TestIfB1())
+}
+
+func TestBitsSimpleEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r BitsSimple
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -1,9 +1,13 @@
 package buffered_struct
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"os"
 	"testing"
 
+	"github.com/go-ee/kaitaigo/runtime"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +31,81 @@ func TestBufferedStruct(t *testing.T) {
 	assert.EqualValues(t, 0x45, r.Block2().Number2())
 	assert.EqualValues(t, 0xee, r.Finisher())
 }
+
+func TestBufferedStructLazy(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/buffered_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r BufferedStruct
+	err = r.DecodeLazy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the blocks are skipped, the fields behind them are parsed
+	assert.True(t, r.Deferred("block1"))
+	assert.True(t, r.Deferred("block2"))
+	assert.EqualValues(t, 0x8, r.Len2())
+	assert.EqualValues(t, 0xee, r.Finisher())
+
+	assert.EqualValues(t, 0x44, r.Block2().Number1())
+	assert.EqualValues(t, 0x45, r.Block2().Number2())
+	assert.False(t, r.Deferred("block2"))
+	assert.True(t, r.Deferred("block1"))
+	assert.EqualValues(t, 0x42, r.Block1().Number1())
+	assert.EqualValues(t, 0x43, r.Block1().Number2())
+	assert.False(t, r.Deferred("block1"))
+	assert.NoError(t, r.Err())
+
+	// materializing restores the position of the stream
+	pos, err := r.Pos()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0x24, pos)
+}
+
+func TestBufferedStructLazyEOF(t *testing.T) {
+	// len1 is larger than the rest of the data
+	var r BufferedStruct
+	err := r.DecodeLazy(bytes.NewReader([]byte{0x10, 0, 0, 0, 0x42, 0, 0, 0}))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+
+	var parseErr *runtime.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	assert.Equal(t, "block1", parseErr.Path)
+	assert.EqualValues(t, 4, parseErr.Pos)
+	assert.False(t, r.Deferred("block1"))
+}
+
+func TestBufferedStructLazyTruncated(t *testing.T) {
+	// block1 is too short for number2, which fails when it is materialized
+	var r BufferedStruct
+	err := r.DecodeLazy(bytes.NewReader([]byte{
+		0x04, 0, 0, 0, 0x42, 0, 0, 0,
+		0x08, 0, 0, 0, 0x44, 0, 0, 0, 0x45, 0, 0, 0,
+		0xee, 0, 0, 0,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, r.Err())
+
+	assert.EqualValues(t, 0x42, r.Block1().Number1())
+	assert.EqualValues(t, 0, r.Block1().Number2())
+	err = r.Err()
+	assert.True(t, errors.Is(err, io.EOF), "%v", err)
+	var parseErr *runtime.ParseError
+	if assert.True(t, errors.As(err, &parseErr), "%v", err) {
+		assert.Equal(t, "block1.number2", parseErr.Path)
+	}
+
+	// later getters succeed, but the first error is kept and block1 is read
+	// again by its getter
+	assert.EqualValues(t, 0x45, r.Block2().Number2())
+	assert.Equal(t, err, r.Err())
+	assert.True(t, r.Deferred("block1"))
+}
@@ -0,0 +1,47 @@
+package debug_0
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebug0(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Debug0
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x50, r.One())
+	assert.EqualValues(t, []uint8{0x41, 0x43, 0x4b}, r.ArrayOfInts())
+	assert.EqualValues(t, 0x2d, r.Unnamed2())
+}
+
+func TestDebug0Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Debug0
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
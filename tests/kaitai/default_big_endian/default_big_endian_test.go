@@ -21,3 +21,25 @@ func TestDefaultBigEndian(t *testing.T) {
 
 	assert.EqualValues(t, 0x7000000, r.One())
 }
+
+func TestDefaultBigEndianEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/enum_0.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r DefaultBigEndian
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
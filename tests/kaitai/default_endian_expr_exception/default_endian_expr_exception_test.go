@@ -0,0 +1,29 @@
+package default_endian_expr_exception
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultEndianExprException(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/endian_expr.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r DefaultEndianExprException
+	err = r.Decode(f)
+	assert.True(t, errors.Is(err, runtime.ErrUndecidedEndianness))
+
+	assert.Len(t, r.Docs(), 2)
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().SomeInt())
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().SomeIntBe())
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().SomeIntLe())
+	assert.EqualValues(t, 0x42, r.Docs()[1].Main().SomeInt())
+	assert.EqualValues(t, 0x42, r.Docs()[1].Main().SomeIntBe())
+	assert.EqualValues(t, 0x42, r.Docs()[1].Main().SomeIntLe())
+}
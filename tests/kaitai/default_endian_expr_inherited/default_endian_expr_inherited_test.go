@@ -0,0 +1,55 @@
+package default_endian_expr_inherited
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultEndianExprInherited(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/endian_expr.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r DefaultEndianExprInherited
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, r.Docs(), 3)
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().Insides().SomeInt())
+	assert.EqualValues(t, 0x4200, r.Docs()[0].Main().Insides().More().SomeInt1())
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().Insides().More().SomeInt2())
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().Insides().More().SomeInst())
+
+	assert.EqualValues(t, 0x42, r.Docs()[1].Main().Insides().SomeInt())
+	assert.EqualValues(t, 0x42, r.Docs()[1].Main().Insides().More().SomeInt1())
+	assert.EqualValues(t, 0x4200, r.Docs()[1].Main().Insides().More().SomeInt2())
+	assert.EqualValues(t, 0x42000000, r.Docs()[1].Main().Insides().More().SomeInst())
+
+	assert.EqualValues(t, 0x42, r.Docs()[2].Main().Insides().SomeInt())
+	assert.EqualValues(t, 0x42, r.Docs()[2].Main().Insides().More().SomeInt1())
+	assert.EqualValues(t, 0x4200, r.Docs()[2].Main().Insides().More().SomeInt2())
+	assert.EqualValues(t, 0x42000000, r.Docs()[2].Main().Insides().More().SomeInst())
+}
+
+func TestDefaultEndianExprInheritedEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/endian_expr.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r DefaultEndianExprInherited
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
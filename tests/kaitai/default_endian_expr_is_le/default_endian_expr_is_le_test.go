@@ -0,0 +1,52 @@
+package default_endian_expr_is_le
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultEndianExprIsLe(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/endian_expr.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r DefaultEndianExprIsLe
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, r.Docs(), 3)
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().SomeInt())
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().SomeIntBe())
+	assert.EqualValues(t, 0x42, r.Docs()[0].Main().SomeIntLe())
+
+	assert.EqualValues(t, 0x42, r.Docs()[1].Main().SomeInt())
+	assert.EqualValues(t, 0x42, r.Docs()[1].Main().SomeIntBe())
+	assert.EqualValues(t, 0x42, r.Docs()[1].Main().SomeIntLe())
+
+	assert.EqualValues(t, 0x42, r.Docs()[2].Main().SomeInt())
+	assert.EqualValues(t, 0x42, r.Docs()[2].Main().SomeIntBe())
+	assert.EqualValues(t, 0x42, r.Docs()[2].Main().SomeIntLe())
+}
+
+func TestDefaultEndianExprIsLeEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/endian_expr.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r DefaultEndianExprIsLe
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
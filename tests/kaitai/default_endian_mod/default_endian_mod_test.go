@@ -23,3 +23,25 @@ func TestDefaultEndianMod(t *testing.T) {
 	assert.EqualValues(t, -52947, r.Main().Nest().Two())
 	assert.EqualValues(t, 0x5041434b, r.Main().NestBe().Two())
 }
+
+func TestDefaultEndianModEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r DefaultEndianMod
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
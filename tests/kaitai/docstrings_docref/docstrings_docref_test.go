@@ -3,6 +3,8 @@ package docstrings_docref
 import (
 	"os"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDocstringsDocref(t *testing.T) {
@@ -18,3 +20,25 @@ func TestDocstringsDocref(t *testing.T) {
 	}
 
 }
+
+func TestDocstringsDocrefEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r DocstringsDocref
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -19,6 +19,6 @@ func TestEnum0(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	assert.Equal(t, Animal.Cat, h.Pet1())
-	assert.Equal(t, Animal.Chicken, h.Pet2())
+	assert.Equal(t, AnimalCat, h.Pet1())
+	assert.Equal(t, AnimalChicken, h.Pet2())
 }
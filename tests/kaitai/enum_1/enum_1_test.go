@@ -0,0 +1,42 @@
+package enum_1
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnum1(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/enum_0.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Enum1
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, MainObjAnimalCat, r.Main().Submain().Pet1())
+	assert.Equal(t, MainObjAnimalChicken, r.Main().Submain().Pet2())
+}
+
+func TestEnum1Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/enum_0.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Enum1
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
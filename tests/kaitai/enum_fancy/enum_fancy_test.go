@@ -14,11 +14,14 @@ func TestEnumFancy(t *testing.T) {
 	}
 
 	var h EnumFancy
-	err = h.Read(s, &h, &h)
+	err = h.Decode(f)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	assert.Equal(t, EnumFancy_Animal__Cat, h.Pet1())
-	assert.Equal(t, EnumFancy_Animal__Chicken, h.Pet2())
+	assert.Equal(t, AnimalCat, h.Pet1())
+	assert.Equal(t, AnimalChicken, h.Pet2())
+	assert.Equal(t, "cat", h.Pet1().String())
+	assert.Equal(t, "MH_FELINE", h.Pet1().OrigID())
+	assert.Equal(t, "", h.Pet2().OrigID())
 }
@@ -0,0 +1,49 @@
+package enum_for_unknown_id
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnumForUnknownId(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r EnumForUnknownId
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 80, r.One())
+	assert.False(t, r.One().IsKnown())
+	assert.Equal(t, "Animal(80)", r.One().String())
+	assert.True(t, AnimalCat.IsKnown())
+	assert.Equal(t, "cat", AnimalCat.String())
+}
+
+func TestEnumForUnknownIdEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r EnumForUnknownId
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -0,0 +1,47 @@
+package enum_if
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnumIf(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/if_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r EnumIf
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, OpcodesAString, r.Op1().Opcode())
+	assert.EqualValues(t, "foo", r.Op1().ArgStr().Str())
+	assert.Equal(t, OpcodesATuple, r.Op2().Opcode())
+	assert.EqualValues(t, 66, r.Op2().ArgTuple().Num1())
+	assert.EqualValues(t, 67, r.Op2().ArgTuple().Num2())
+	assert.Equal(t, OpcodesAString, r.Op3().Opcode())
+	assert.EqualValues(t, "bar", r.Op3().ArgStr().Str())
+}
+
+func TestEnumIfEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/if_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r EnumIf
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
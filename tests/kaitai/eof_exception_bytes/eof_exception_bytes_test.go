@@ -0,0 +1,30 @@
+package eof_exception_bytes
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEofExceptionBytes(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r EofExceptionBytes
+	err = r.Decode(f)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+
+	var parseErr *runtime.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	assert.Equal(t, "EofExceptionBytes", parseErr.Type)
+	assert.Equal(t, "buf", parseErr.Path)
+	assert.EqualValues(t, 12, parseErr.Pos)
+}
@@ -0,0 +1,33 @@
+package eof_exception_io
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEofExceptionIo(t *testing.T) {
+	var r EofExceptionIo
+	err := r.DecodeBytes([]byte{1, 2, 3, 4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 1, r.Body().Magic())
+	assert.EqualValues(t, 0x0605, r.Tail())
+
+	r.BodyTail()
+	err = r.Err()
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+
+	// the position is the one in the body, where the read failed, not the
+	// one of the root stream
+	var parseErr *runtime.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	assert.Equal(t, "body_tail", parseErr.Path)
+	assert.EqualValues(t, 4, parseErr.Pos)
+}
@@ -0,0 +1,31 @@
+package eof_exception_nested
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEofExceptionNested(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r EofExceptionNested
+	err = r.Decode(f)
+	assert.True(t, errors.Is(err, io.EOF), "%v", err)
+
+	var parseErr *runtime.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	assert.Equal(t, "Entry", parseErr.Type)
+	assert.Equal(t, "header.entries[3].name", parseErr.Path)
+	assert.EqualValues(t, 12, parseErr.Pos)
+	assert.Equal(t, "parsing header.entries[3].name of Entry at pos 12: EOF", err.Error())
+}
@@ -0,0 +1,31 @@
+package eof_exception_u4
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEofExceptionU4(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r EofExceptionU4
+	err = r.Decode(f)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+
+	var parseErr *runtime.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	assert.Equal(t, "EofExceptionU4", parseErr.Type)
+	assert.Equal(t, "fail_int", parseErr.Path)
+	assert.EqualValues(t, 12, parseErr.Pos)
+	assert.Equal(t, []byte{0x66, 0x6f, 0x6f, 0x7c, 0x62, 0x61, 0x72, 0x7c, 0x62}, r.Prebuf())
+}
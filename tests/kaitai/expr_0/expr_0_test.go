@@ -25,3 +25,25 @@ func TestExpr0(t *testing.T) {
 	mustBeAbc123 := r.MustBeAbc123()
 	assert.EqualValues(t, "abc123", mustBeAbc123)
 }
+
+func TestExpr0Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/str_encodings.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Expr0
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -24,3 +24,25 @@ func TestExpr1(t *testing.T) {
 	assert.EqualValues(t, "Some ASC", r.Str1())
 	assert.EqualValues(t, 8, r.Str1Len())
 }
+
+func TestExpr1Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/str_encodings.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Expr1
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -42,3 +42,25 @@ func TestExpr2(t *testing.T) {
 	assert.EqualValues(t, 0x41, str2Tuple5.Byte2())
 	assert.EqualValues(t, 0x30, str2Tuple5.Avg())
 }
+
+func TestExpr2Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/str_encodings.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Expr2
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -35,3 +35,25 @@ func TestExpr3(t *testing.T) {
 	assert.EqualValues(t, true, r.IsStrLt2())
 	assert.EqualValues(t, true, r.TestNot())
 }
+
+func TestExpr3Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Expr3
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -38,3 +38,21 @@ func TestExprArray(t *testing.T) {
 	assert.EqualValues(t, "bar", r.AstrMin())
 	assert.EqualValues(t, "foo", r.AstrMax())
 }
+
+func TestExprArrayEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/expr_array.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ExprArray
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -0,0 +1,94 @@
+package expr_array_empty
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExprArrayEmpty(t *testing.T) {
+	var r ExprArrayEmpty
+	err := r.DecodeBytes([]byte{2, 1, 0, 0, 0, 9, 0, 0, 0, '4', '2', '7', 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 1, r.NumsFirst())
+	assert.EqualValues(t, 9, r.NumsLast())
+	assert.EqualValues(t, 1, r.NumsMin())
+	assert.EqualValues(t, 9, r.NumsMax())
+	assert.EqualValues(t, 427, r.NumStrI())
+	assert.Equal(t, "18446744073709551615", r.BigS())
+	assert.NoError(t, r.Err())
+}
+
+func TestExprArrayEmptyErrors(t *testing.T) {
+	for name, get := range map[string]func(r *ExprArrayEmpty){
+		"nums_first": func(r *ExprArrayEmpty) { r.NumsFirst() },
+		"nums_last":  func(r *ExprArrayEmpty) { r.NumsLast() },
+		"nums_min":   func(r *ExprArrayEmpty) { r.NumsMin() },
+		"nums_max":   func(r *ExprArrayEmpty) { r.NumsMax() },
+	} {
+		var r ExprArrayEmpty
+		err := r.DecodeBytes([]byte{0, '4', '2', '7', 0, 0, 0, 0, 0, 0, 0, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		get(&r)
+		err = r.Err()
+		assert.True(t, errors.Is(err, runtime.ErrEmptyArray), "%s: %v", name, err)
+
+		var parseErr *runtime.ParseError
+		if assert.True(t, errors.As(err, &parseErr), name) {
+			assert.Equal(t, name, parseErr.Path)
+		}
+	}
+
+	var r ExprArrayEmpty
+	err := r.DecodeBytes([]byte{0, '4', '2', 'x', 0, 0, 0, 0, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 0, r.NumStrI())
+	err = r.Err()
+	assert.True(t, errors.Is(err, strconv.ErrSyntax), "%v", err)
+	var exprErr *runtime.ExprError
+	if assert.True(t, errors.As(err, &exprErr)) {
+		assert.Equal(t, "to_i", exprErr.Method)
+	}
+}
+
+func TestExprArrayEmptyRead(t *testing.T) {
+	// the endianness and the skipped sizes are calculated while reading the
+	// type in lazy mode
+	for path, get := range map[string]func(r *ExprArrayEmpty){
+		"endian_first":   func(r *ExprArrayEmpty) { r.EndianFirst() },
+		"sized_max.body": func(r *ExprArrayEmpty) { r.SizedMax() },
+	} {
+		var r ExprArrayEmpty
+		err := r.DecodeLazy(bytes.NewReader([]byte{0, '4', '2', '7', 0, 0, 0, 0, 0, 0, 0, 0}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		get(&r)
+		err = r.Err()
+		assert.True(t, errors.Is(err, runtime.ErrEmptyArray), "%s: %v", path, err)
+
+		var parseErr *runtime.ParseError
+		if assert.True(t, errors.As(err, &parseErr), path) {
+			assert.Equal(t, path, parseErr.Path)
+		}
+	}
+
+	var r ExprArrayEmpty
+	err := r.DecodeLazy(bytes.NewReader([]byte{2, 1, 0, 0, 0, 9, 0, 0, 0, '4', '2', '7', 0, 0, 0, 0, 0, 0, 0, 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 0x0102, r.EndianFirst().Value())
+	assert.Equal(t, []byte{2, 1, 0, 0, 0, 9, 0, 0, 0}, r.SizedMax().Body())
+	assert.NoError(t, r.Err())
+}
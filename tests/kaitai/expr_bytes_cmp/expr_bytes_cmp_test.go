@@ -33,3 +33,25 @@ func TestExprBytesCmp(t *testing.T) {
 	assert.EqualValues(t, false, r.IsLt2())
 	assert.EqualValues(t, true, r.IsGt2())
 }
+
+func TestExprBytesCmpEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ExprBytesCmp
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -0,0 +1,28 @@
+package expr_io_eof
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExprIoEof(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ExprIoEof
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x4b434150, r.Substream1().One())
+	assert.EqualValues(t, 0, r.Substream1().Two())
+	assert.True(t, r.Substream1().ReflectEof())
+	assert.EqualValues(t, 0xffff312d, r.Substream2().One())
+	assert.EqualValues(t, 0x4b434150, r.Substream2().Two())
+	assert.True(t, r.Substream2().ReflectEof())
+}
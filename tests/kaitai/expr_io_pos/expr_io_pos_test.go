@@ -29,3 +29,21 @@ func TestExprIoPos(t *testing.T) {
 	assert.EqualValues(t, []uint8{97, 32, 99, 97, 116}, r.Substream2().Body())
 	assert.EqualValues(t, 103, r.Substream2().Number())
 }
+
+func TestExprIoPosEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/expr_io_pos.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ExprIoPos
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
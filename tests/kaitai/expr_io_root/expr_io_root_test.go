@@ -0,0 +1,20 @@
+package expr_io_root
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExprIoRoot(t *testing.T) {
+	var r ExprIoRoot
+	err := r.DecodeBytes([]byte{0x01, 'a', 'b', 'c', 0x09})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x01, r.Header())
+	assert.Equal(t, []byte("abc"), r.Body())
+	assert.EqualValues(t, 0x09, r.Last())
+	assert.EqualValues(t, 0, r.Extra())
+}
@@ -29,3 +29,25 @@ func TestExprMod(t *testing.T) {
 	assert.EqualValues(t, 5, r.ModPosSeq())
 	assert.EqualValues(t, 2, r.ModNegSeq())
 }
+
+func TestExprModEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ExprMod
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestFixedContents(t *testing.T) {
@@ -21,3 +23,25 @@ func TestFixedContents(t *testing.T) {
 	}
 
 }
+
+func TestFixedContentsEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r FixedContents
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -31,3 +31,25 @@ func TestFloatToI(t *testing.T) {
 	assert.EqualValues(t, 1, r.Float3I())
 	assert.EqualValues(t, -2, r.Float4I())
 }
+
+func TestFloatToIEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/floating_points.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r FloatToI
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
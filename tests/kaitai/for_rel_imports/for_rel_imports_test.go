@@ -0,0 +1,47 @@
+package for_rel_imports
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImported1(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Imported1
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// imported_2 is in the same directory and thus in the same package
+	assert.EqualValues(t, 0x50, r.One())
+	assert.EqualValues(t, 0x41, r.Two().One())
+}
+
+func TestImported1Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Imported1
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -20,3 +20,25 @@ func TestHelloWorld(t *testing.T) {
 
 	assert.EqualValues(t, 80, r.One())
 }
+
+func TestHelloWorldEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai//fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r HelloWorld
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
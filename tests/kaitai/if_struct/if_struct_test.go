@@ -30,3 +30,21 @@ func TestIfStruct(t *testing.T) {
 	assert.EqualValues(t, 83, r.Op3().Opcode())
 	assert.EqualValues(t, "bar", r.Op3().ArgStr().Str())
 }
+
+func TestIfStructEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/if_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r IfStruct
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
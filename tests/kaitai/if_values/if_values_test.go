@@ -32,3 +32,25 @@ func TestIfValues(t *testing.T) {
 	// assert.Nil(t, codes[2].HalfOpcode()) uint cannot be nil
 	assert.EqualValues(t, 0, codes[2].HalfOpcode())
 }
+
+func TestIfValuesEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r IfValues
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
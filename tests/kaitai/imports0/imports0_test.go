@@ -0,0 +1,50 @@
+// Autogenerated from KST: please remove this line if doing any edits by hand!
+
+package imports0
+
+import (
+	"os"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImports0(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Imports0
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x50, r.Two())
+	assert.EqualValues(t, 0x41, r.Hw().One())
+	assert.EqualValues(t, 0x41, r.HwOne())
+}
+
+func TestImports0Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Imports0
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -0,0 +1,49 @@
+// Autogenerated from KST: please remove this line if doing any edits by hand!
+
+package imports_abs
+
+import (
+	"os"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportsAbs(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ImportsAbs
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x50, r.Len().Value())
+	assert.EqualValues(t, 0x50, len(r.Body()))
+}
+
+func TestImportsAbsEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ImportsAbs
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
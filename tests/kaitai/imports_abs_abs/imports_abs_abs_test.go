@@ -0,0 +1,50 @@
+// Autogenerated from KST: please remove this line if doing any edits by hand!
+
+package imports_abs_abs
+
+import (
+	"os"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportsAbsAbs(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ImportsAbsAbs
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x50, r.One())
+	assert.EqualValues(t, 0x41, r.Two().One())
+	assert.EqualValues(t, 0x43, r.Two().Two().One())
+}
+
+func TestImportsAbsAbsEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ImportsAbsAbs
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -0,0 +1,52 @@
+// Autogenerated from KST: please remove this line if doing any edits by hand!
+
+package imports_circular_a
+
+import (
+	"os"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportsCircularA(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ImportsCircularA
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x50, r.Code())
+	assert.EqualValues(t, 0x41, r.Two().Initial())
+	assert.EqualValues(t, 0x43, r.Two().BackRef().Code())
+	assert.EqualValues(t, 0x4b, r.Two().BackRef().Two().Initial())
+	assert.Nil(t, r.Two().BackRef().Two().BackRef())
+}
+
+func TestImportsCircularAEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ImportsCircularA
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
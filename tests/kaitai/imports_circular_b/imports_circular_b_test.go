@@ -0,0 +1,22 @@
+package imports_circular_b
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportsCircularB(t *testing.T) {
+	var r ImportsCircularB
+	err := r.DecodeBytes([]byte{0x41, 0x50, 0x42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// imports_circular_a is embedded into this package, as Go does not
+	// allow the import cycle
+	assert.EqualValues(t, 0x41, r.Initial())
+	assert.EqualValues(t, 0x50, r.BackRef().Code())
+	assert.EqualValues(t, 0x42, r.BackRef().Two().Initial())
+	assert.Nil(t, r.BackRef().Two().BackRef())
+}
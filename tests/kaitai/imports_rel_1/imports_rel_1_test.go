@@ -0,0 +1,52 @@
+package imports_rel_1
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/tests/kaitai/for_rel_imports"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportsRel1(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ImportsRel1
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x50, r.One())
+	assert.EqualValues(t, 0x41, r.Two().One())
+	assert.EqualValues(t, 0x43, r.Two().Two().One())
+
+	// imported types of other directories are referenced from their package
+	assert.IsType(t, &for_rel_imports.Imported1{}, r.Two())
+	assert.IsType(t, &for_rel_imports.Imported2{}, r.Two().Two())
+}
+
+func TestImportsRel1Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ImportsRel1
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -0,0 +1,20 @@
+package instance_io_root
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceIoRoot(t *testing.T) {
+	var r InstanceIoRoot
+	err := r.DecodeBytes([]byte{0x03, 0x34, 0x12, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 0x1234, r.Body().Value())
+	assert.EqualValues(t, 3, r.Body().RootLen())
+	assert.EqualValues(t, 0xff, r.Body().Last())
+	assert.NoError(t, r.Err())
+}
@@ -0,0 +1,96 @@
+package instance_io_user
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceIoUser(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/instance_io.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r InstanceIoUser
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 3, r.QtyEntries())
+	assert.Len(t, r.Entries(), 3)
+	assert.EqualValues(t, "the", r.Entries()[0].Name())
+	assert.EqualValues(t, "rainy", r.Entries()[1].Name())
+	assert.EqualValues(t, "day it is", r.Entries()[2].Name())
+}
+
+func TestInstanceIoUserLazy(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/instance_io.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r InstanceIoUser
+	err = r.DecodeLazy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the names are read from the substream of the deferred strings
+	assert.True(t, r.Deferred("strings"))
+	assert.Len(t, r.Entries(), 3)
+	assert.EqualValues(t, "day it is", r.Entries()[2].Name())
+	assert.EqualValues(t, "the", r.Entries()[0].Name())
+	assert.False(t, r.Deferred("strings"))
+	assert.NoError(t, r.Entries()[0].Err())
+	assert.NoError(t, r.Err())
+}
+
+func TestInstanceIoUserNoStream(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/instance_io.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r InstanceIoUser
+	err = r.DecodeLazy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// strings has no stream to read the names from
+	r.SetStrings(nil)
+	assert.Equal(t, "", r.Entries()[0].Name())
+	err = r.Entries()[0].Err()
+	assert.True(t, errors.Is(err, runtime.ErrNoStream), "%v", err)
+
+	var parseErr *runtime.ParseError
+	if assert.True(t, errors.As(err, &parseErr), "%v", err) {
+		assert.Equal(t, "name", parseErr.Path)
+		assert.Equal(t, "Entry", parseErr.Type)
+	}
+}
+
+func TestInstanceIoUserEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/instance_io.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r InstanceIoUser
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
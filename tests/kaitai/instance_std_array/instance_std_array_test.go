@@ -30,3 +30,25 @@ func TestInstanceStdArray(t *testing.T) {
 	assert.EqualValues(t, []uint8{34, 34, 34, 34}, r.Entries()[1])
 	assert.EqualValues(t, []uint8{51, 51, 51, 51}, r.Entries()[2])
 }
+
+func TestInstanceStdArrayEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/instance_std_array.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r InstanceStdArray
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
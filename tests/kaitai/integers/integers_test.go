@@ -43,3 +43,21 @@ func TestIntegers(t *testing.T) {
 	assert.EqualValues(t, -66, r.Sint32Be())
 	assert.EqualValues(t, -66, r.Sint64Be())
 }
+
+func TestIntegersEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r Integers
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
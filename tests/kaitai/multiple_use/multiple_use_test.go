@@ -25,3 +25,25 @@ func TestMultipleUse(t *testing.T) {
 	assert.EqualValues(t, 32, r.T1().FirstUse().Value())
 	assert.EqualValues(t, 32, r.T2().SecondUse().Value())
 }
+
+func TestMultipleUseEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/position_abs.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r MultipleUse
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
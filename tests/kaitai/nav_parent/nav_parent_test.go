@@ -28,3 +28,21 @@ func TestNavParent(t *testing.T) {
 	assert.EqualValues(t, "FIRST___", r.Index().Entries()[0].Filename())
 	assert.EqualValues(t, "SECOND__", r.Index().Entries()[1].Filename())
 }
+
+func TestNavParentEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/nav.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r NavParent
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
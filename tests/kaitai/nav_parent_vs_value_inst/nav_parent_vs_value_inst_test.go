@@ -24,3 +24,25 @@ func TestNavParentVsValueInst(t *testing.T) {
 
 	assert.EqualValues(t, "foo", r.S1())
 }
+
+func TestNavParentVsValueInstEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r NavParentVsValueInst
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -25,3 +25,25 @@ func TestNestedSameName(t *testing.T) {
 	assert.EqualValues(t, 2, r.MainData().MainSize())
 	assert.EqualValues(t, []uint8{16, 0, 0, 0}, r.MainData().Foo().Data())
 }
+
+func TestNestedSameNameEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_n_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r NestedSameName
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -26,3 +26,25 @@ func TestNestedTypes(t *testing.T) {
 	assert.EqualValues(t, 65, r.One().TypedHere().ValueC())
 	assert.EqualValues(t, 67, r.Two().ValueB())
 }
+
+func TestNestedTypesEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r NestedTypes
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
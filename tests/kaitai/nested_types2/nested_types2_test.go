@@ -30,3 +30,25 @@ func TestNestedTypes2(t *testing.T) {
 	assert.EqualValues(t, 49, r.One().TypedHere2().ValueCc())
 	assert.EqualValues(t, -1, r.Two().ValueB())
 }
+
+func TestNestedTypes2Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r NestedTypes2
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
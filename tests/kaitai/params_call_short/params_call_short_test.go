@@ -0,0 +1,50 @@
+// Autogenerated from KST: please remove this line if doing any edits by hand!
+
+package params_call_short
+
+import (
+	"os"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamsCallShort(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ParamsCallShort
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, "foo|b", r.Buf1().Body())
+	assert.EqualValues(t, "ar|ba", r.Buf2().Body())
+	assert.EqualValues(t, 0x7a, r.Buf2().Trailer())
+}
+
+func TestParamsCallShortEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ParamsCallShort
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -0,0 +1,31 @@
+package params_def
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamsDef(t *testing.T) {
+	r := NewParamsDef(5, true)
+	err := r.DecodeFile("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 5, r.Len())
+	assert.EqualValues(t, true, r.HasTrailer())
+	assert.EqualValues(t, "foo|b", r.Buf())
+	assert.EqualValues(t, 0x61, r.Trailer())
+}
+
+func TestParamsDefWithoutTrailer(t *testing.T) {
+	r := NewParamsDef(3, false)
+	err := r.DecodeBytes([]byte("foo|"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, "foo", r.Buf())
+	assert.EqualValues(t, 0, r.Trailer())
+}
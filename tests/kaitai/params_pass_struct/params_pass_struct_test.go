@@ -0,0 +1,47 @@
+// Autogenerated from KST: please remove this line if doing any edits by hand!
+
+package params_pass_struct
+
+import (
+	"os"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamsPassStruct(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/enum_negative.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ParamsPassStruct
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 255, r.First().Foo())
+	assert.EqualValues(t, 1, r.One().Bar().Qux())
+	assert.EqualValues(t, 255, r.One().Foo().(*Block).Foo())
+	assert.EqualValues(t, 255, r.One().Bar().Foo().(*Block).Foo())
+}
+
+func TestParamsPassStructEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/enum_negative.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ParamsPassStruct
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -0,0 +1,49 @@
+// Autogenerated from KST: please remove this line if doing any edits by hand!
+
+package params_pass_usertype
+
+import (
+	"os"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamsPassUsertype(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/position_in_seq.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ParamsPassUsertype
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, 1, r.First().Foo())
+	assert.EqualValues(t, []uint8{2}, r.One().Buf())
+}
+
+func TestParamsPassUsertypeEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/position_in_seq.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ParamsPassUsertype
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -25,3 +25,25 @@ func TestPositionAbs(t *testing.T) {
 	assert.EqualValues(t, 32, r.IndexOffset())
 	assert.EqualValues(t, "foo", r.Index().Entry())
 }
+
+func TestPositionAbsEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/position_abs.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r PositionAbs
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
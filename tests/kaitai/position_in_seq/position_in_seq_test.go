@@ -23,3 +23,25 @@ func TestPositionInSeq(t *testing.T) {
 	assert.EqualValues(t, 3, r.Header().QtyNumbers())
 	assert.EqualValues(t, []uint8{1, 2, 3}, r.Numbers())
 }
+
+func TestPositionInSeqEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/position_in_seq.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r PositionInSeq
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
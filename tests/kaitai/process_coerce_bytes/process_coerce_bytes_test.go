@@ -28,3 +28,21 @@ func TestProcessCoerceBytes(t *testing.T) {
 	assert.EqualValues(t, 1, records[1].Flag())
 	assert.EqualValues(t, []uint8{66, 66, 66, 66}, records[1].Buf())
 }
+
+func TestProcessCoerceBytesEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/process_coerce_bytes.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ProcessCoerceBytes
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -1,5 +1,3 @@
-// Autogenerated from KST: please remove this line if doing any edits by hand!
-
 package process_rotate
 
 import (
@@ -26,3 +24,21 @@ func TestProcessRotate(t *testing.T) {
 	assert.EqualValues(t, []uint8{87, 111, 114, 108, 100}, r.Buf2())
 	assert.EqualValues(t, []uint8{84, 104, 101, 114, 101}, r.Buf3())
 }
+
+func TestProcessRotateEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/process_rotate.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ProcessRotate
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
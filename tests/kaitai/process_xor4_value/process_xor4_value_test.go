@@ -25,3 +25,21 @@ func TestProcessXor4Value(t *testing.T) {
 	assert.EqualValues(t, []uint8{236, 187, 163, 20}, r.Key())
 	assert.EqualValues(t, []uint8{102, 111, 111, 32, 98, 97, 114}, r.Buf())
 }
+
+func TestProcessXor4ValueEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/process_xor_4.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ProcessXor4Value
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
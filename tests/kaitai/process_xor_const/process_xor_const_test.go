@@ -1,5 +1,3 @@
-// Autogenerated from KST: please remove this line if doing any edits by hand!
-
 package process_xor_const
 
 import (
@@ -25,3 +23,21 @@ func TestProcessXorConst(t *testing.T) {
 	assert.EqualValues(t, 255, r.Key())
 	assert.EqualValues(t, []uint8{102, 111, 111, 32, 98, 97, 114}, r.Buf())
 }
+
+func TestProcessXorConstEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/process_xor_1.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ProcessXorConst
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -25,3 +25,21 @@ func TestProcessXorValue(t *testing.T) {
 	assert.EqualValues(t, 255, r.Key())
 	assert.EqualValues(t, []uint8{102, 111, 111, 32, 98, 97, 114}, r.Buf())
 }
+
+func TestProcessXorValueEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/process_xor_1.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ProcessXorValue
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -1,5 +1,3 @@
-// Autogenerated from KST: please remove this line if doing any edits by hand!
-
 package repeat_eos_struct
 
 import (
@@ -30,3 +28,21 @@ func TestRepeatEosStruct(t *testing.T) {
 	assert.EqualValues(t, 66, r.Chunks()[1].Offset())
 	assert.EqualValues(t, 2069, r.Chunks()[1].Len())
 }
+
+func TestRepeatEosStructEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_eos_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatEosStruct
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
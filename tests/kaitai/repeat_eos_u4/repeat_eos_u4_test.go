@@ -22,3 +22,21 @@ func TestRepeatEosU4(t *testing.T) {
 
 	assert.EqualValues(t, []uint32{0, 66, 66, 2069}, r.Numbers())
 }
+
+func TestRepeatEosU4Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_eos_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatEosU4
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -28,3 +28,21 @@ func TestRepeatNStruct(t *testing.T) {
 	assert.EqualValues(t, 8328, r.Chunks()[1].Offset())
 	assert.EqualValues(t, 15, r.Chunks()[1].Len())
 }
+
+func TestRepeatNStructEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_n_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatNStruct
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -27,3 +27,21 @@ func TestRepeatNStrz(t *testing.T) {
 	assert.EqualValues(t, "foo", r.Lines()[0])
 	assert.EqualValues(t, "bar", r.Lines()[1])
 }
+
+func TestRepeatNStrzEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_n_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatNStrz
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -26,3 +26,21 @@ func TestRepeatNStrzDouble(t *testing.T) {
 	assert.EqualValues(t, "foo", r.Lines1()[0])
 	assert.EqualValues(t, "bar", r.Lines2()[0])
 }
+
+func TestRepeatNStrzDoubleEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_n_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatNStrzDouble
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
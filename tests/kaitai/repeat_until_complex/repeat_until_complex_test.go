@@ -38,3 +38,21 @@ func TestRepeatUntilComplex(t *testing.T) {
 	assert.EqualValues(t, 0, r.Second()[3].Count())
 	assert.EqualValues(t, []byte{(0 + 102), 111, 111, 98, 97, 114, 0}, r.Third())
 }
+
+func TestRepeatUntilComplexEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_until_complex.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatUntilComplex
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -23,3 +23,21 @@ func TestRepeatUntilS4(t *testing.T) {
 	assert.EqualValues(t, []int32{66, 4919, -251658241, -1}, r.Entries())
 	assert.EqualValues(t, "foobar", r.Afterall())
 }
+
+func TestRepeatUntilS4Encode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_until_s4.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatUntilS4
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
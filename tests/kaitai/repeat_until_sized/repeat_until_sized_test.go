@@ -0,0 +1,47 @@
+package repeat_until_sized
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatUntilSized(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/repeat_until_process.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatUntilSized
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, r.Records(), 3)
+	assert.EqualValues(t, 0xe8, r.Records()[0].Marker())
+	assert.EqualValues(t, 0xaaaaaaba, r.Records()[0].Body())
+	assert.EqualValues(t, 0xfa, r.Records()[1].Marker())
+	assert.EqualValues(t, 0xaaaab89e, r.Records()[1].Body())
+	assert.EqualValues(t, 0xaa, r.Records()[2].Marker())
+	assert.EqualValues(t, 0x55555555, r.Records()[2].Body())
+}
+
+func TestRepeatUntilSizedEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_until_process.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r RepeatUntilSized
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
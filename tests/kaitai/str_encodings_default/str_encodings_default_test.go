@@ -27,3 +27,21 @@ func TestStrEncodingsDefault(t *testing.T) {
 	assert.EqualValues(t, "\u3053\u3093\u306b\u3061\u306f", r.Rest().Str3())
 	assert.EqualValues(t, "\u2591\u2592\u2593", r.Rest().Str4())
 }
+
+func TestStrEncodingsDefaultEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/str_encodings.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r StrEncodingsDefault
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -24,3 +24,21 @@ func TestStrEos(t *testing.T) {
 
 	assert.EqualValues(t, "foo|bar|baz@", r.Str())
 }
+
+func TestStrEosEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r StrEos
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
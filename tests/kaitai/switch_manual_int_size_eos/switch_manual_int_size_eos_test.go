@@ -0,0 +1,59 @@
+package switch_manual_int_size_eos
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwitchManualIntSizeEos(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/switch_tlv.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r SwitchManualIntSizeEos
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, r.Chunks(), 4)
+
+	assert.EqualValues(t, 0x11, r.Chunks()[0].Code())
+	assert.EqualValues(t, 9, r.Chunks()[0].Size())
+	assert.EqualValues(t, "Stuff", r.Chunks()[0].Body().Body().(*ChunkMeta).Title())
+	assert.EqualValues(t, "Me", r.Chunks()[0].Body().Body().(*ChunkMeta).Author())
+
+	assert.EqualValues(t, 0x22, r.Chunks()[1].Code())
+	assert.EqualValues(t, 12, r.Chunks()[1].Size())
+	assert.EqualValues(t, []string{"AAAA", "BBBB", "CCCC"}, r.Chunks()[1].Body().Body().(*ChunkDir).Entries())
+
+	assert.EqualValues(t, 0x33, r.Chunks()[2].Code())
+	assert.EqualValues(t, 8, r.Chunks()[2].Size())
+	assert.EqualValues(t, []byte{0x10, 0x20, 0x30, 0x40, 0x50, 0x60, 0x70, 0x80}, r.Chunks()[2].Body().Body().(*runtime.RawBytes).Data)
+
+	assert.EqualValues(t, 0xff, r.Chunks()[3].Code())
+	assert.EqualValues(t, 0, r.Chunks()[3].Size())
+	assert.Empty(t, r.Chunks()[3].Body().Body().(*runtime.RawBytes).Data)
+}
+
+func TestSwitchManualIntSizeEosEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/switch_tlv.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r SwitchManualIntSizeEos
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
@@ -26,3 +26,21 @@ func TestTermBytes(t *testing.T) {
 	assert.EqualValues(t, "\x62\x61\x72", r.S2())
 	assert.EqualValues(t, "\x7C\x62\x61\x7A\x40", r.S3())
 }
+
+func TestTermBytesEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r TermBytes
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
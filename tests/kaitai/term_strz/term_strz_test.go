@@ -1,5 +1,3 @@
-// Autogenerated from KST: please remove this line if doing any edits by hand!
-
 package term_strz
 
 import (
@@ -26,3 +24,21 @@ func TestTermStrz(t *testing.T) {
 	assert.EqualValues(t, "bar", r.S2())
 	assert.EqualValues(t, "|baz@", r.S3())
 }
+
+func TestTermStrzEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/term_strz.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r TermStrz
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, data, out)
+}
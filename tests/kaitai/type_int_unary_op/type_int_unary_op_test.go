@@ -29,3 +29,25 @@ func TestTypeIntUnaryOp(t *testing.T) {
 	tmp2 := r.UnaryS8()
 	assert.EqualValues(t, -4706543082108963651, tmp2)
 }
+
+func TestTypeIntUnaryOpEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r TypeIntUnaryOp
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -25,3 +25,25 @@ func TestUserType(t *testing.T) {
 	assert.EqualValues(t, 66, r.One().Width())
 	assert.EqualValues(t, 4919, r.One().Height())
 }
+
+func TestUserTypeEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/repeat_until_s4.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r UserType
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
@@ -0,0 +1,28 @@
+package valid_fail_anyof_int
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFailAnyofInt(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidFailAnyofInt
+	err = r.Decode(f)
+
+	var validationErr *runtime.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	assert.Equal(t, runtime.ValidationNotAnyOf, validationErr.Kind)
+	assert.EqualValues(t, 0x50, validationErr.Actual)
+	assert.Equal(t, "/seq/0 at pos 0: validation failed, not any of: expected [5 6 7 8 10 11 12 47], got 80", validationErr.Error())
+}
@@ -0,0 +1,31 @@
+package valid_fail_contents
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFailContents(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidFailContents
+	err = r.Decode(f)
+
+	var dataErr *runtime.UnexpectedDataError
+	if !errors.As(err, &dataErr) {
+		t.Fatalf("expected an unexpected data error, got %v", err)
+	}
+	assert.Equal(t, "/seq/1", dataErr.Path)
+	assert.EqualValues(t, 6, dataErr.Pos)
+	assert.Equal(t, []byte{0xff, 0xff}, dataErr.Actual)
+	assert.Equal(t, []byte{0xfe, 0xff}, dataErr.Expected)
+	assert.Equal(t, "/seq/1 at pos 6: unexpected data, expected fe ff, got ff ff", dataErr.Error())
+	assert.Equal(t, []byte("PACK-1"), r.Magic())
+}
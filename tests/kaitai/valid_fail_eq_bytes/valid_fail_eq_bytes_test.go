@@ -0,0 +1,29 @@
+package valid_fail_eq_bytes
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFailEqBytes(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidFailEqBytes
+	err = r.Decode(f)
+
+	var validationErr *runtime.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	assert.Equal(t, runtime.ValidationNotEqual, validationErr.Kind)
+	assert.Equal(t, "/seq/0", validationErr.Path)
+	assert.Equal(t, []byte{0x50, 0x41}, validationErr.Actual)
+	assert.Equal(t, []byte{0x51, 0x41}, validationErr.Expected)
+}
@@ -0,0 +1,30 @@
+package valid_fail_eq_int
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFailEqInt(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidFailEqInt
+	err = r.Decode(f)
+
+	var validationErr *runtime.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	assert.Equal(t, runtime.ValidationNotEqual, validationErr.Kind)
+	assert.Equal(t, "/seq/0", validationErr.Path)
+	assert.EqualValues(t, 0, validationErr.Pos)
+	assert.EqualValues(t, 0x50, validationErr.Actual)
+	assert.EqualValues(t, 123, validationErr.Expected)
+}
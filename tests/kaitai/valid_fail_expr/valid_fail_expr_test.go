@@ -0,0 +1,31 @@
+package valid_fail_expr
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFailExpr(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidFailExpr
+	err = r.Decode(f)
+
+	var validationErr *runtime.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	assert.Equal(t, runtime.ValidationExprFailed, validationErr.Kind)
+	assert.Equal(t, "/seq/1", validationErr.Path)
+	assert.EqualValues(t, 1, validationErr.Pos)
+	assert.EqualValues(t, 17217, validationErr.Actual)
+	assert.Equal(t, "_ < 17217 or _ > 17217", validationErr.Expected)
+	assert.EqualValues(t, 0x50, r.Foo())
+}
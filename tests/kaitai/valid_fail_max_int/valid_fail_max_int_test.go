@@ -0,0 +1,28 @@
+package valid_fail_max_int
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFailMaxInt(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidFailMaxInt
+	err = r.Decode(f)
+
+	var validationErr *runtime.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	assert.Equal(t, runtime.ValidationGreaterThan, validationErr.Kind)
+	assert.EqualValues(t, 0x50, validationErr.Actual)
+	assert.EqualValues(t, 12, validationErr.Expected)
+}
@@ -0,0 +1,34 @@
+package valid_fail_repeat_contents
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFailRepeatContents(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidFailRepeatContents
+	err = r.Decode(f)
+
+	// the file starts with "PACK-1", so the second element is "CK"
+	var dataErr *runtime.UnexpectedDataError
+	if !errors.As(err, &dataErr) {
+		t.Fatalf("expected an unexpected data error, got %v", err)
+	}
+	assert.EqualValues(t, []byte{0x43, 0x4b}, dataErr.Actual)
+	assert.EqualValues(t, []byte{0x50, 0x41}, dataErr.Expected)
+	assert.EqualValues(t, 2, dataErr.Pos)
+
+	var parseErr *runtime.ParseError
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, "foo[1]", parseErr.Path)
+	}
+}
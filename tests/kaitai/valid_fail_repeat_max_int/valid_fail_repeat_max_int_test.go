@@ -0,0 +1,44 @@
+package valid_fail_repeat_max_int
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ee/kaitaigo/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFailRepeatMaxInt(t *testing.T) {
+	var r ValidFailRepeatMaxInt
+	err := r.DecodeBytes([]byte{9, 1, 2})
+
+	var validationErr *runtime.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	assert.Equal(t, runtime.ValidationGreaterThan, validationErr.Kind)
+	assert.EqualValues(t, 9, validationErr.Actual)
+	assert.EqualValues(t, 0, validationErr.Pos)
+
+	var parseErr *runtime.ParseError
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, "foo[0]", parseErr.Path)
+	}
+}
+
+func TestValidFailRepeatEosMaxInt(t *testing.T) {
+	var r ValidFailRepeatMaxInt
+	err := r.DecodeBytes([]byte{1, 2, 3, 4, 7, 5})
+
+	var validationErr *runtime.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	assert.EqualValues(t, 7, validationErr.Actual)
+	assert.EqualValues(t, 4, validationErr.Pos)
+
+	var parseErr *runtime.ParseError
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, "bar[1]", parseErr.Path)
+	}
+}
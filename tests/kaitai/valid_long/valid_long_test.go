@@ -0,0 +1,51 @@
+package valid_long
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidLong(t *testing.T) {
+	f, err := os.Open("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidLong
+	err = r.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []byte("PACK-1"), r.Magic1())
+	assert.EqualValues(t, 255, r.Uint8())
+	assert.EqualValues(t, -1, r.Sint8())
+	assert.Equal(t, "PACK-U-DEF", r.MagicUint())
+	assert.EqualValues(t, uint64(18446744073709551615), r.Uint64())
+	assert.Equal(t, "PACK-S-DEF", r.MagicSint())
+	assert.EqualValues(t, -1, r.Sint64())
+}
+
+func TestValidLongEncode(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/kaitai/fixed_struct.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r ValidLong
+	if err = r.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := r.Pos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data[:pos], out)
+}
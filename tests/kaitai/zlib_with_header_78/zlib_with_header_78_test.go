@@ -1,5 +1,3 @@
-// Autogenerated from KST: please remove this line if doing any edits by hand!
-
 package zlib_with_header_78
 
 import (
@@ -24,3 +22,19 @@ func TestZlibWithHeader78(t *testing.T) {
 
 	assert.EqualValues(t, []uint8{97, 32, 113, 117, 105, 99, 107, 32, 98, 114, 111, 119, 110, 32, 102, 111, 120, 32, 106, 117, 109, 112, 115, 32, 111, 118, 101, 114}, r.Data())
 }
+
+func TestZlibWithHeader78Encode(t *testing.T) {
+	var r ZlibWithHeader78
+	r.SetData([]byte("a quick brown fox jumps over"))
+	data, err := r.EncodeBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded ZlibWithHeader78
+	if err = decoded.DecodeBytes(data); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualValues(t, "a quick brown fox jumps over", decoded.Data())
+}